@@ -0,0 +1,98 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// drainState takes the container out of rotation: while draining, /ready
+// fails so App Platform stops routing traffic, but /health keeps passing so
+// the container isn't restarted. It lives in memory and resets on restart.
+type drainState struct {
+	mu     sync.RWMutex
+	since  time.Time
+	reason string
+}
+
+var drain = &drainState{}
+
+func (d *drainState) set(reason string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.since.IsZero() {
+		d.since = time.Now().UTC()
+	}
+	d.reason = reason
+}
+
+func (d *drainState) clear() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.since = time.Time{}
+	d.reason = ""
+}
+
+// status reports whether the container is draining, since when and why.
+func (d *drainState) status() (bool, time.Time, string) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return !d.since.IsZero(), d.since, d.reason
+}
+
+type DrainResponse struct {
+	Draining bool   `json:"draining"`
+	Since    string `json:"since,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+func currentDrainResponse() DrainResponse {
+	draining, since, reason := drain.status()
+	response := DrainResponse{Draining: draining, Reason: reason}
+	if draining {
+		response.Since = since.Format(time.RFC3339)
+	}
+	return response
+}
+
+// requireAdmin rejects anything but POST, and refuses to run at all unless
+// AUTH_TOKEN is set, since requireAuth lets every request through without
+// one.
+func requireAdmin(w http.ResponseWriter, r *http.Request) bool {
+	if os.Getenv("AUTH_TOKEN") == "" {
+		writeError(w, http.StatusForbidden, "admin endpoints are disabled; set AUTH_TOKEN to enable them")
+		return false
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, "use POST")
+		return false
+	}
+	return true
+}
+
+// drainHandler serves POST /admin/drain[?reason=].
+func drainHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+	reason := r.URL.Query().Get("reason")
+	if reason == "" {
+		reason = "drained by operator"
+	}
+	drain.set(reason)
+	slog.Warn("draining: /ready will fail until POST /admin/undrain", "reason", reason)
+	writeJSON(w, http.StatusOK, currentDrainResponse())
+}
+
+// undrainHandler serves POST /admin/undrain.
+func undrainHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+	drain.clear()
+	slog.Info("drain cleared: /ready reports dependency status again")
+	writeJSON(w, http.StatusOK, currentDrainResponse())
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// unauthenticatedPaths stay open so App Platform's probes keep working.
+var unauthenticatedPaths = map[string]bool{
+	"/health": true,
+	"/ready":  true,
+}
+
+// requireAuth enforces "Authorization: Bearer <AUTH_TOKEN>" on every path
+// except the health probes. It is a no-op when AUTH_TOKEN is unset.
+func requireAuth(next http.Handler) http.Handler {
+	token := os.Getenv("AUTH_TOKEN")
+	if token == "" {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if unauthenticatedPaths[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+		provided, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="debug-container"`)
+			writeError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
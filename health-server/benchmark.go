@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultBenchmarkRuns = 10
+	maxBenchmarkRuns     = 100
+	// maxBenchmarkDuration bounds a whole /benchmark request, staying under
+	// the default REQUEST_TIMEOUT.
+	maxBenchmarkDuration = 20 * time.Second
+)
+
+type BenchmarkResult struct {
+	Target    string    `json:"target"`
+	Requested int       `json:"requested"`
+	Completed int       `json:"completed"`
+	Failures  int       `json:"failures"`
+	MinMs     float64   `json:"min_ms"`
+	AvgMs     float64   `json:"avg_ms"`
+	P50Ms     float64   `json:"p50_ms"`
+	P95Ms     float64   `json:"p95_ms"`
+	MaxMs     float64   `json:"max_ms"`
+	SamplesMs []float64 `json:"samples_ms"`
+	Truncated bool      `json:"truncated,omitempty"`
+	Note      string    `json:"note"`
+	LastError string    `json:"last_error,omitempty"`
+}
+
+// percentile returns the nearest-rank percentile of sorted samples.
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// runBenchmark runs check n times in sequence and summarizes the latency of
+// the successful runs. It stops early, marking the result truncated, once
+// ctx is done.
+func runBenchmark(ctx context.Context, check dbCheck, n int, timeout time.Duration) BenchmarkResult {
+	result := BenchmarkResult{
+		Target:    check.Name,
+		Requested: n,
+		SamplesMs: []float64{},
+		Note:      "each run opens a new connection, so samples include connection setup and TLS handshake time",
+	}
+
+	for i := 0; i < n; i++ {
+		if ctx.Err() != nil {
+			result.Truncated = true
+			break
+		}
+		runCtx, cancel := context.WithTimeout(ctx, timeout)
+		start := time.Now()
+		outcome, ok := check.Run(runCtx)
+		elapsed := time.Since(start)
+		cancel()
+		// A run cut off by the overall deadline says nothing about latency.
+		if ctx.Err() != nil {
+			result.Truncated = true
+			break
+		}
+
+		result.Completed++
+		if !ok {
+			result.Failures++
+			result.LastError = checkResultError(outcome)
+			continue
+		}
+		result.SamplesMs = append(result.SamplesMs, latencyMs(elapsed))
+	}
+
+	if len(result.SamplesMs) == 0 {
+		return result
+	}
+	sorted := append([]float64(nil), result.SamplesMs...)
+	sort.Float64s(sorted)
+	var total float64
+	for _, sample := range sorted {
+		total += sample
+	}
+	result.MinMs = sorted[0]
+	result.MaxMs = sorted[len(sorted)-1]
+	result.AvgMs = math.Round(total/float64(len(sorted))*1000) / 1000
+	result.P50Ms = percentile(sorted, 50)
+	result.P95Ms = percentile(sorted, 95)
+	return result
+}
+
+// benchmarkHandler serves /benchmark?target=postgres&n=20.
+func benchmarkHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	target := query.Get("target")
+	var names []string
+	var check *dbCheck
+	for i := range dbChecks {
+		names = append(names, dbChecks[i].Name)
+		if dbChecks[i].Name == target {
+			check = &dbChecks[i]
+		}
+	}
+	if check == nil {
+		writeError(w, http.StatusBadRequest, "target must be one of "+strings.Join(names, ", "))
+		return
+	}
+	if !check.Configured() {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("%s is not configured", target))
+		return
+	}
+
+	n := defaultBenchmarkRuns
+	if val := query.Get("n"); val != "" {
+		parsed, err := strconv.Atoi(val)
+		if err != nil || parsed < 1 || parsed > maxBenchmarkRuns {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("n must be a number between 1 and %d", maxBenchmarkRuns))
+			return
+		}
+		n = parsed
+	}
+
+	// Leave time to write the response if REQUEST_TIMEOUT is shorter.
+	deadline := time.Now().Add(maxBenchmarkDuration)
+	if requestDeadline, ok := r.Context().Deadline(); ok && requestDeadline.Add(-time.Second).Before(deadline) {
+		deadline = requestDeadline.Add(-time.Second)
+	}
+	ctx, cancel := context.WithDeadline(r.Context(), deadline)
+	defer cancel()
+
+	result := runBenchmark(ctx, *check, n, checkTimeout(r.Context()))
+	writeCheckResult(w, len(result.SamplesMs) > 0, result)
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+type AutoCheckResult struct {
+	Type   string      `json:"type"`
+	OK     bool        `json:"ok"`
+	Error  string      `json:"error,omitempty"`
+	Result interface{} `json:"result"`
+}
+
+// autoCheckSuffixes are the env var suffixes App Platform bind variables use
+// for connection strings.
+var autoCheckSuffixes = []string{"_URL", "_URI", "_BROKERS"}
+
+// inferDatabaseType guesses which check applies to an env var from its
+// connection string's scheme. It returns "" for values it can't classify.
+func inferDatabaseType(key, value string) string {
+	scheme, _, found := strings.Cut(value, "://")
+	scheme = strings.ToLower(scheme)
+	switch {
+	case !found && strings.HasSuffix(key, "_BROKERS"):
+		return "kafka"
+	case !found:
+		return ""
+	case scheme == "postgres" || scheme == "postgresql":
+		return "postgres"
+	case scheme == "mysql":
+		return "mysql"
+	case scheme == "redis" || scheme == "rediss":
+		return "redis"
+	case scheme == "mongodb" || scheme == "mongodb+srv":
+		return "mongodb"
+	case scheme == "kafka":
+		return "kafka"
+	case (scheme == "http" || scheme == "https") && strings.Contains(key, "OPENSEARCH"):
+		return "opensearch"
+	}
+	return ""
+}
+
+// runAutoCheck runs the check matching dbType against value.
+func runAutoCheck(ctx context.Context, dbType, value string) (interface{}, bool) {
+	switch dbType {
+	case "postgres":
+		result := checkPostgres(ctx, value)
+		return result, result.Connected
+	case "mysql":
+		result := checkMySQL(ctx, value)
+		return result, result.Connected
+	case "redis":
+		result := checkRedis(ctx, value)
+		return result, result.Connected
+	case "mongodb":
+		result := checkMongoDB(ctx, value)
+		return result, result.Connected
+	case "opensearch":
+		result := checkOpenSearch(ctx, value)
+		return result, result.Connected
+	default:
+		var brokers []string
+		for _, broker := range strings.Split(value, ",") {
+			broker = strings.TrimPrefix(strings.TrimSpace(broker), "kafka://")
+			if broker != "" {
+				brokers = append(brokers, broker)
+			}
+		}
+		result := checkKafka(ctx, brokers)
+		return result, len(result.ReachableBrokers) > 0 && len(result.UnreachableBrokers) == 0
+	}
+}
+
+// discoverConnectionStrings returns every env var ending in _URL, _URI or
+// _BROKERS whose value maps to a known database type.
+func discoverConnectionStrings() map[string]string {
+	found := make(map[string]string)
+	for _, kv := range os.Environ() {
+		key, value, _ := strings.Cut(kv, "=")
+		if value == "" {
+			continue
+		}
+		for _, suffix := range autoCheckSuffixes {
+			if strings.HasSuffix(key, suffix) {
+				if dbType := inferDatabaseType(key, value); dbType != "" {
+					found[key] = dbType
+				}
+				break
+			}
+		}
+	}
+	return found
+}
+
+// autoCheckHandler checks every connection string found in the environment,
+// keyed by env var name, without needing to know in advance which are set.
+func autoCheckHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), checkTimeout(r.Context()))
+	defer cancel()
+
+	discovered := discoverConnectionStrings()
+	keys := make([]string, 0, len(discovered))
+	for key := range discovered {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	results := make(map[string]AutoCheckResult)
+	allOK := true
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, key := range keys {
+		wg.Add(1)
+		go func(key, dbType string) {
+			defer wg.Done()
+			result, ok := runAutoCheck(ctx, dbType, os.Getenv(key))
+			outcome := AutoCheckResult{Type: dbType, OK: ok, Result: result}
+			if !ok {
+				outcome.Error = checkResultError(result)
+			}
+			mu.Lock()
+			results[key] = outcome
+			if !ok {
+				allOK = false
+			}
+			mu.Unlock()
+		}(key, discovered[key])
+	}
+	wg.Wait()
+	writeCheckResult(w, allOK, results)
+}
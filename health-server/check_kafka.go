@@ -0,0 +1,522 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/segmentio/kafka-go/sasl/scram"
+)
+
+type KafkaBrokerError struct {
+	Broker        string `json:"broker"`
+	Error         string `json:"error"`
+	ErrorCategory string `json:"error_category,omitempty"`
+}
+
+type KafkaCheckResult struct {
+	ReachableBrokers   []string           `json:"reachable_brokers"`
+	UnreachableBrokers []KafkaBrokerError `json:"unreachable_brokers"`
+	TopicCount         int                `json:"topic_count"`
+	SASLMechanism      string             `json:"sasl_mechanism,omitempty"`
+	TLS                bool               `json:"tls"`
+	ClientCert         bool               `json:"client_cert"`
+	Topic              *KafkaTopicResult  `json:"topic,omitempty"`
+	Hint               string             `json:"hint,omitempty"`
+	EgressIP           string             `json:"egress_ip,omitempty"`
+	Attempts           int                `json:"attempts,omitempty"`
+	Error              string             `json:"error,omitempty"`
+}
+
+type KafkaPartitionResult struct {
+	Partition       int    `json:"partition"`
+	Leader          string `json:"leader"`
+	Replicas        int    `json:"replicas"`
+	InSyncReplicas  int    `json:"in_sync_replicas"`
+	LogEndOffset    int64  `json:"log_end_offset"`
+	CommittedOffset *int64 `json:"committed_offset,omitempty"`
+	Lag             *int64 `json:"lag,omitempty"`
+	Error           string `json:"error,omitempty"`
+}
+
+type KafkaTopicResult struct {
+	Name       string                 `json:"name"`
+	Partitions []KafkaPartitionResult `json:"partitions"`
+	Group      string                 `json:"group,omitempty"`
+	TotalLag   *int64                 `json:"total_lag,omitempty"`
+	Error      string                 `json:"error,omitempty"`
+}
+
+// getKafkaBrokers returns the configured broker list, accepting the
+// KAFKA_BROKER/KAFKA_HOST variants used by validate-infra.
+func getKafkaBrokers() []string {
+	raw := os.Getenv("KAFKA_BROKERS")
+	if raw == "" {
+		raw = os.Getenv("KAFKA_BROKER")
+	}
+	if raw == "" {
+		if host := os.Getenv("KAFKA_HOST"); host != "" {
+			port := os.Getenv("KAFKA_PORT")
+			if port == "" {
+				port = "25073"
+			}
+			raw = host + ":" + port
+		}
+	}
+
+	var brokers []string
+	for _, broker := range strings.Split(raw, ",") {
+		if broker = strings.TrimSpace(broker); broker != "" {
+			brokers = append(brokers, broker)
+		}
+	}
+	return brokers
+}
+
+// kafkaSASLMechanism builds the SASL mechanism from KAFKA_SASL_MECHANISM,
+// KAFKA_SASL_USERNAME and KAFKA_SASL_PASSWORD (falling back to
+// KAFKA_USERNAME/KAFKA_PASSWORD). DigitalOcean managed Kafka uses
+// SCRAM-SHA-256, which is the default when credentials are present.
+func kafkaSASLMechanism() (sasl.Mechanism, string, error) {
+	username := os.Getenv("KAFKA_SASL_USERNAME")
+	if username == "" {
+		username = os.Getenv("KAFKA_USERNAME")
+	}
+	password := os.Getenv("KAFKA_SASL_PASSWORD")
+	if password == "" {
+		password = os.Getenv("KAFKA_PASSWORD")
+	}
+	if username == "" {
+		return nil, "", nil
+	}
+
+	name := strings.ToUpper(os.Getenv("KAFKA_SASL_MECHANISM"))
+	switch name {
+	case "", "SCRAM-SHA-256":
+		mechanism, err := scram.Mechanism(scram.SHA256, username, password)
+		return mechanism, "SCRAM-SHA-256", err
+	case "SCRAM-SHA-512":
+		mechanism, err := scram.Mechanism(scram.SHA512, username, password)
+		return mechanism, name, err
+	case "PLAIN":
+		return plain.Mechanism{Username: username, Password: password}, name, nil
+	default:
+		return nil, "", fmt.Errorf("unsupported KAFKA_SASL_MECHANISM %q", name)
+	}
+}
+
+// kafkaTLSConfig returns a TLS config when KAFKA_TLS=true, SASL is in use,
+// or a CA or client certificate is provided. KAFKA_CA_CERT holds PEM
+// content, possibly with escaped newlines.
+func kafkaTLSConfig(saslEnabled bool) (*tls.Config, error) {
+	caCert := os.Getenv("KAFKA_CA_CERT")
+	if caCert == "" {
+		caCert = os.Getenv("CA_CERT")
+	}
+	clientCert, err := loadClientCert("KAFKA")
+	if err != nil {
+		return nil, err
+	}
+	if os.Getenv("KAFKA_TLS") != "true" && !saslEnabled && caCert == "" && clientCert == nil {
+		return nil, nil
+	}
+
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if clientCert != nil {
+		cfg.Certificates = []tls.Certificate{*clientCert}
+	}
+	if caCert != "" {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM([]byte(strings.ReplaceAll(caCert, `\n`, "\n"))) {
+			return nil, fmt.Errorf("KAFKA_CA_CERT does not contain a valid PEM certificate")
+		}
+		cfg.RootCAs = pool
+	}
+	return cfg, nil
+}
+
+// newKafkaDialer builds a dialer honoring the SASL and TLS settings.
+func newKafkaDialer(ctx context.Context) (*kafka.Dialer, string, error) {
+	mechanism, mechanismName, err := kafkaSASLMechanism()
+	if err != nil {
+		return nil, "", err
+	}
+	tlsConfig, err := kafkaTLSConfig(mechanism != nil)
+	if err != nil {
+		return nil, "", err
+	}
+	dialer := &kafka.Dialer{
+		Timeout:       checkTimeout(ctx),
+		DualStack:     true,
+		TLS:           tlsConfig,
+		SASLMechanism: mechanism,
+	}
+	if useCheckDialer(ctx) {
+		dialer.DialFunc = dialOutbound
+	}
+	return dialer, mechanismName, nil
+}
+
+// checkKafka dials every broker concurrently and counts topics using the
+// metadata from the first reachable broker.
+func checkKafka(ctx context.Context, brokers []string) KafkaCheckResult {
+	result := KafkaCheckResult{
+		ReachableBrokers:   []string{},
+		UnreachableBrokers: []KafkaBrokerError{},
+	}
+
+	for _, broker := range brokers {
+		// Brokers are host:port pairs rather than URLs, so only the bind
+		// variable case can be caught up front.
+		if strings.Contains(broker, "${") {
+			result.Error = errMalformedConnString.Error()
+			return result
+		}
+	}
+
+	dialer, mechanismName, err := newKafkaDialer(ctx)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.SASLMechanism = mechanismName
+	result.TLS = dialer.TLS != nil
+	result.ClientCert = dialer.TLS != nil && len(dialer.TLS.Certificates) > 0
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	topics := -1
+	var timedOut error
+	for _, broker := range brokers {
+		wg.Add(1)
+		go func(broker string) {
+			defer wg.Done()
+			conn, err := dialer.DialContext(ctx, "tcp", broker)
+			if err != nil {
+				mu.Lock()
+				result.UnreachableBrokers = append(result.UnreachableBrokers, KafkaBrokerError{Broker: broker, Error: err.Error(), ErrorCategory: errorCategory(err)})
+				if isTimeoutError(err) {
+					timedOut = err
+				}
+				mu.Unlock()
+				return
+			}
+			defer trackClient("kafka", conn.Close)()
+			if deadline, ok := ctx.Deadline(); ok {
+				conn.SetDeadline(deadline)
+			}
+
+			count := -1
+			if partitions, err := conn.ReadPartitions(); err == nil {
+				names := make(map[string]bool)
+				for _, p := range partitions {
+					names[p.Topic] = true
+				}
+				count = len(names)
+			}
+
+			mu.Lock()
+			result.ReachableBrokers = append(result.ReachableBrokers, broker)
+			if count > topics {
+				topics = count
+			}
+			mu.Unlock()
+		}(broker)
+	}
+	wg.Wait()
+
+	sort.Strings(result.ReachableBrokers)
+	sort.Slice(result.UnreachableBrokers, func(i, j int) bool {
+		return result.UnreachableBrokers[i].Broker < result.UnreachableBrokers[j].Broker
+	})
+	if topics >= 0 {
+		result.TopicCount = topics
+	}
+	result.Hint, result.EgressIP = timeoutHint(timedOut)
+	if topic := os.Getenv("KAFKA_TOPIC"); topic != "" && len(result.ReachableBrokers) > 0 {
+		result.Topic = checkKafkaTopic(ctx, dialer, result.ReachableBrokers, topic, os.Getenv("KAFKA_GROUP"))
+	}
+	return result
+}
+
+// newKafkaClient builds a request-level client for brokers that shares the
+// dialer's dial function, timeout, TLS and SASL settings. Callers close the transport's
+// idle connections when done.
+func newKafkaClient(dialer *kafka.Dialer, brokers []string) (*kafka.Client, *kafka.Transport) {
+	transport := &kafka.Transport{
+		Dial:        dialer.DialFunc,
+		DialTimeout: dialer.Timeout,
+		TLS:         dialer.TLS,
+		SASL:        dialer.SASLMechanism,
+	}
+	return &kafka.Client{Addr: kafka.TCP(brokers...), Transport: transport}, transport
+}
+
+// checkKafkaTopic reports the partition leaders and log end offsets of topic
+// and, when group is set, the group's committed offsets and lag per
+// partition. Every request shares ctx, so a stuck broker can't outlive the
+// check timeout.
+func checkKafkaTopic(ctx context.Context, dialer *kafka.Dialer, brokers []string, topic string, group string) *KafkaTopicResult {
+	result := &KafkaTopicResult{Name: topic, Partitions: []KafkaPartitionResult{}, Group: group}
+
+	client, transport := newKafkaClient(dialer, brokers)
+	defer trackClient("kafka", func() error {
+		transport.CloseIdleConnections()
+		return nil
+	})()
+
+	metadata, err := client.Metadata(ctx, &kafka.MetadataRequest{Topics: []string{topic}})
+	if err != nil {
+		result.Error = fmt.Sprintf("metadata request failed: %v", err)
+		return result
+	}
+	if len(metadata.Topics) == 0 {
+		result.Error = "topic not found"
+		return result
+	}
+	if err := metadata.Topics[0].Error; err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	var offsetRequests []kafka.OffsetRequest
+	var partitionIDs []int
+	for _, p := range metadata.Topics[0].Partitions {
+		partition := KafkaPartitionResult{
+			Partition:      p.ID,
+			Leader:         fmt.Sprintf("%d (%s:%d)", p.Leader.ID, p.Leader.Host, p.Leader.Port),
+			Replicas:       len(p.Replicas),
+			InSyncReplicas: len(p.Isr),
+		}
+		if p.Error != nil {
+			partition.Error = p.Error.Error()
+		}
+		result.Partitions = append(result.Partitions, partition)
+		offsetRequests = append(offsetRequests, kafka.LastOffsetOf(p.ID))
+		partitionIDs = append(partitionIDs, p.ID)
+	}
+	sort.Slice(result.Partitions, func(i, j int) bool {
+		return result.Partitions[i].Partition < result.Partitions[j].Partition
+	})
+
+	offsets, err := client.ListOffsets(ctx, &kafka.ListOffsetsRequest{
+		Topics: map[string][]kafka.OffsetRequest{topic: offsetRequests},
+	})
+	if err != nil {
+		result.Error = fmt.Sprintf("list offsets failed: %v", err)
+		return result
+	}
+	endOffsets := make(map[int]int64)
+	for _, p := range offsets.Topics[topic] {
+		endOffsets[p.Partition] = p.LastOffset
+	}
+	for i := range result.Partitions {
+		result.Partitions[i].LogEndOffset = endOffsets[result.Partitions[i].Partition]
+	}
+
+	if group == "" {
+		return result
+	}
+	committed, err := client.OffsetFetch(ctx, &kafka.OffsetFetchRequest{
+		GroupID: group,
+		Topics:  map[string][]int{topic: partitionIDs},
+	})
+	if err == nil {
+		err = committed.Error
+	}
+	if err != nil {
+		result.Error = fmt.Sprintf("offset fetch for group %q failed: %v", group, err)
+		return result
+	}
+	committedOffsets := make(map[int]int64)
+	for _, p := range committed.Topics[topic] {
+		committedOffsets[p.Partition] = p.CommittedOffset
+	}
+
+	var total int64
+	for i := range result.Partitions {
+		partition := &result.Partitions[i]
+		offset, ok := committedOffsets[partition.Partition]
+		if !ok || offset < 0 {
+			// The group has never committed on this partition.
+			continue
+		}
+		lag := partition.LogEndOffset - offset
+		partition.CommittedOffset = &offset
+		partition.Lag = &lag
+		total += lag
+	}
+	result.TotalLag = &total
+	return result
+}
+
+// kafkaCheckHandler answers 200 when every broker is reachable, 207 when only
+// some are, and 503 when none are.
+func kafkaCheckHandler(w http.ResponseWriter, r *http.Request) {
+	brokers := getKafkaBrokers()
+	if len(brokers) == 0 {
+		writeCheckResult(w, false, KafkaCheckResult{Error: "KAFKA_BROKERS is not set"})
+		return
+	}
+
+	result, attempts := retryCheck(r.Context(), func(ctx context.Context) (KafkaCheckResult, bool) {
+		result := checkKafka(ctx, brokers)
+		return result, len(result.ReachableBrokers) > 0 && len(result.UnreachableBrokers) == 0
+	})
+	result.Attempts = attempts
+	recordCheckResult("kafka", len(result.ReachableBrokers) > 0 && len(result.UnreachableBrokers) == 0)
+
+	status := http.StatusOK
+	switch {
+	case len(result.ReachableBrokers) == 0:
+		status = http.StatusServiceUnavailable
+	case len(result.UnreachableBrokers) > 0:
+		status = http.StatusMultiStatus
+	}
+	writeJSON(w, status, result)
+}
+
+// kafkaRoundTripPartition is the partition /check/kafka/roundtrip writes to;
+// every topic has one.
+const kafkaRoundTripPartition = 0
+
+type KafkaRoundTripResult struct {
+	Topic         string  `json:"topic"`
+	Partition     int     `json:"partition"`
+	Key           string  `json:"key"`
+	Offset        *int64  `json:"offset,omitempty"`
+	Produced      bool    `json:"produced"`
+	Consumed      bool    `json:"consumed"`
+	ProduceMs     float64 `json:"produce_ms,omitempty"`
+	RoundTripMs   float64 `json:"round_trip_ms,omitempty"`
+	Error         string  `json:"error,omitempty"`
+	ErrorCategory string  `json:"error_category,omitempty"`
+}
+
+// checkKafkaRoundTrip produces a uniquely keyed record to topic and fetches
+// it back by offset. Fetching a partition directly rather than through a
+// consumer group leaves nothing behind on the cluster besides the record.
+func checkKafkaRoundTrip(ctx context.Context, brokers []string, topic string) KafkaRoundTripResult {
+	result := KafkaRoundTripResult{Topic: topic, Partition: kafkaRoundTripPartition}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Key = "do-app-debug-container-roundtrip-" + hex.EncodeToString(id)
+	value := fmt.Sprintf("round-trip test from %s at %s", getContainerName(), time.Now().UTC().Format(time.RFC3339Nano))
+
+	dialer, _, err := newKafkaDialer(ctx)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	client, transport := newKafkaClient(dialer, brokers)
+	defer trackClient("kafka", func() error {
+		transport.CloseIdleConnections()
+		return nil
+	})()
+
+	start := time.Now()
+	produced, err := client.Produce(ctx, &kafka.ProduceRequest{
+		Topic:        topic,
+		Partition:    kafkaRoundTripPartition,
+		RequiredAcks: kafka.RequireAll,
+		Records: kafka.NewRecordReader(kafka.Record{
+			Key:   kafka.NewBytes([]byte(result.Key)),
+			Value: kafka.NewBytes([]byte(value)),
+			Time:  start,
+		}),
+	})
+	if err == nil {
+		err = produced.Error
+	}
+	if err != nil {
+		result.Error = fmt.Sprintf("produce failed: %v", err)
+		result.ErrorCategory = errorCategory(err)
+		return result
+	}
+	result.Produced = true
+	result.ProduceMs = latencyMs(time.Since(start))
+	offset := produced.BaseOffset
+	result.Offset = &offset
+
+	// Fetches may return batches that begin before the requested offset, so
+	// read until the record itself turns up.
+	next := offset
+	for !result.Consumed {
+		fetched, err := client.Fetch(ctx, &kafka.FetchRequest{
+			Topic:     topic,
+			Partition: kafkaRoundTripPartition,
+			Offset:    next,
+			MinBytes:  1,
+			MaxBytes:  1 << 20,
+			MaxWait:   500 * time.Millisecond,
+		})
+		if err == nil {
+			err = fetched.Error
+		}
+		if err != nil {
+			result.Error = fmt.Sprintf("consume failed: %v", err)
+			result.ErrorCategory = errorCategory(err)
+			return result
+		}
+		for {
+			record, err := fetched.Records.ReadRecord()
+			if err != nil {
+				break
+			}
+			if record.Offset >= next {
+				next = record.Offset + 1
+			}
+			key, _ := kafka.ReadAll(record.Key)
+			if record.Offset == offset && string(key) == result.Key {
+				result.Consumed = true
+				break
+			}
+		}
+		if !result.Consumed && next > offset {
+			result.Error = fmt.Sprintf("record at offset %d did not carry the test key", offset)
+			return result
+		}
+	}
+	result.RoundTripMs = latencyMs(time.Since(start))
+	return result
+}
+
+// kafkaRoundTripHandler writes to the cluster, so it only runs when
+// ENABLE_KAFKA_ROUNDTRIP=true.
+func kafkaRoundTripHandler(w http.ResponseWriter, r *http.Request) {
+	if os.Getenv("ENABLE_KAFKA_ROUNDTRIP") != "true" {
+		writeError(w, http.StatusForbidden, "round-trip test is disabled; set ENABLE_KAFKA_ROUNDTRIP=true to let it produce to KAFKA_TEST_TOPIC")
+		return
+	}
+	brokers := getKafkaBrokers()
+	if len(brokers) == 0 {
+		writeCheckResult(w, false, KafkaRoundTripResult{Error: "KAFKA_BROKERS is not set"})
+		return
+	}
+	topic := os.Getenv("KAFKA_TEST_TOPIC")
+	if topic == "" {
+		writeCheckResult(w, false, KafkaRoundTripResult{Error: "KAFKA_TEST_TOPIC is not set"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), checkTimeout(r.Context()))
+	defer cancel()
+	result := checkKafkaRoundTrip(ctx, brokers, topic)
+	writeCheckResult(w, result.Consumed, result)
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+type MongoDBCheckResult struct {
+	Connected     bool    `json:"connected"`
+	LatencyMs     float64 `json:"latency_ms"`
+	Topology      string  `json:"topology,omitempty"`
+	ReplicaSet    string  `json:"replica_set,omitempty"`
+	Primary       string  `json:"primary,omitempty"`
+	Hint          string  `json:"hint,omitempty"`
+	EgressIP      string  `json:"egress_ip,omitempty"`
+	Attempts      int     `json:"attempts,omitempty"`
+	Error         string  `json:"error,omitempty"`
+	ErrorCategory string  `json:"error_category,omitempty"`
+}
+
+// checkMongoDB pings the deployment at uri and reports its topology using the
+// hello command. Both mongodb:// and mongodb+srv:// URIs are supported.
+func checkMongoDB(ctx context.Context, uri string) MongoDBCheckResult {
+	if err := validateConnString(uri, "mongodb"); err != nil {
+		return MongoDBCheckResult{Error: err.Error()}
+	}
+	// SRV resolution inside the driver isn't context-aware, so run the whole
+	// check in a goroutine and stop waiting when the deadline passes.
+	done := make(chan MongoDBCheckResult, 1)
+	go func() {
+		done <- runMongoDBCheck(ctx, uri)
+	}()
+	select {
+	case result := <-done:
+		return result
+	case <-ctx.Done():
+		result := MongoDBCheckResult{Error: "timed out: " + ctx.Err().Error(), ErrorCategory: errorCategoryTimeout}
+		result.Hint, result.EgressIP = timeoutHint(ctx.Err())
+		return result
+	}
+}
+
+func runMongoDBCheck(ctx context.Context, uri string) MongoDBCheckResult {
+	var result MongoDBCheckResult
+
+	timeout := checkTimeout(ctx)
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline)
+	}
+	opts := options.Client().ApplyURI(uri).
+		SetServerSelectionTimeout(timeout).
+		SetConnectTimeout(timeout)
+	if useCheckDialer(ctx) {
+		opts.SetDialer(checkDialer{ipVersion: ipVersion(ctx)})
+	}
+	client, err := mongo.Connect(opts)
+	if err != nil {
+		result.Error = redactConnStrings(err.Error())
+		return result
+	}
+	defer trackClient("mongodb", func() error { return client.Disconnect(context.Background()) })()
+
+	start := time.Now()
+	if err := client.Ping(ctx, nil); err != nil {
+		result.Error = err.Error()
+		result.ErrorCategory = errorCategory(err)
+		result.Hint, result.EgressIP = timeoutHint(err)
+		return result
+	}
+	result.LatencyMs = latencyMs(time.Since(start))
+	result.Connected = true
+
+	var hello struct {
+		SetName string `bson:"setName"`
+		Primary string `bson:"primary"`
+		Msg     string `bson:"msg"`
+	}
+	err = client.Database("admin").RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello)
+	if err != nil {
+		result.Error = "connected, but hello failed: " + err.Error()
+		return result
+	}
+	switch {
+	case hello.SetName != "":
+		result.Topology = "replica_set"
+		result.ReplicaSet = hello.SetName
+		result.Primary = hello.Primary
+	case hello.Msg == "isdbgrid":
+		result.Topology = "sharded"
+	default:
+		result.Topology = "standalone"
+	}
+	return result
+}
+
+func mongodbCheckHandler(w http.ResponseWriter, r *http.Request) {
+	uri := os.Getenv("MONGODB_URI")
+	if uri == "" {
+		writeCheckResult(w, false, MongoDBCheckResult{Error: "MONGODB_URI is not set"})
+		return
+	}
+
+	result, attempts := retryCheck(r.Context(), func(ctx context.Context) (MongoDBCheckResult, bool) {
+		result := checkMongoDB(ctx, uri)
+		return result, result.Connected
+	})
+	result.Attempts = attempts
+	recordCheckResult("mongodb", result.Connected)
+	writeCheckResult(w, result.Connected, result)
+}
+
+type MongoDBMemberStatus struct {
+	Name       string   `json:"name"`
+	State      string   `json:"state"`
+	Healthy    bool     `json:"healthy"`
+	Self       bool     `json:"self,omitempty"`
+	SyncSource string   `json:"sync_source,omitempty"`
+	PingMs     *int64   `json:"ping_ms,omitempty"`
+	OptimeDate string   `json:"optime_date,omitempty"`
+	LagSeconds *float64 `json:"lag_seconds,omitempty"`
+}
+
+type MongoDBReplicaSetResult struct {
+	ReplicaSet string                `json:"replica_set,omitempty"`
+	Primary    string                `json:"primary,omitempty"`
+	Members    []MongoDBMemberStatus `json:"members"`
+	Error      string                `json:"error,omitempty"`
+}
+
+// checkMongoDBReplicaSet runs replSetGetStatus and reports each member's
+// state and how far its last applied operation trails the primary's.
+func checkMongoDBReplicaSet(ctx context.Context, uri string) MongoDBReplicaSetResult {
+	if err := validateConnString(uri, "mongodb"); err != nil {
+		return MongoDBReplicaSetResult{Members: []MongoDBMemberStatus{}, Error: err.Error()}
+	}
+	done := make(chan MongoDBReplicaSetResult, 1)
+	go func() {
+		done <- runMongoDBReplicaSetCheck(ctx, uri)
+	}()
+	select {
+	case result := <-done:
+		return result
+	case <-ctx.Done():
+		return MongoDBReplicaSetResult{Members: []MongoDBMemberStatus{}, Error: "timed out: " + ctx.Err().Error()}
+	}
+}
+
+func runMongoDBReplicaSetCheck(ctx context.Context, uri string) MongoDBReplicaSetResult {
+	result := MongoDBReplicaSetResult{Members: []MongoDBMemberStatus{}}
+
+	timeout := checkTimeout(ctx)
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline)
+	}
+	opts := options.Client().ApplyURI(uri).
+		SetServerSelectionTimeout(timeout).
+		SetConnectTimeout(timeout)
+	if useCheckDialer(ctx) {
+		opts.SetDialer(checkDialer{ipVersion: ipVersion(ctx)})
+	}
+	client, err := mongo.Connect(opts)
+	if err != nil {
+		result.Error = redactConnStrings(err.Error())
+		return result
+	}
+	defer trackClient("mongodb", func() error { return client.Disconnect(context.Background()) })()
+
+	var status struct {
+		Set     string `bson:"set"`
+		Members []struct {
+			Name           string    `bson:"name"`
+			Health         float64   `bson:"health"`
+			StateStr       string    `bson:"stateStr"`
+			Self           bool      `bson:"self"`
+			SyncSourceHost string    `bson:"syncSourceHost"`
+			PingMs         *int64    `bson:"pingMs"`
+			OptimeDate     time.Time `bson:"optimeDate"`
+		} `bson:"members"`
+	}
+	err = client.Database("admin").RunCommand(ctx, bson.D{{Key: "replSetGetStatus", Value: 1}}).Decode(&status)
+	if err != nil {
+		var cmdErr mongo.CommandError
+		switch {
+		case errors.As(err, &cmdErr) && cmdErr.Code == 13:
+			result.Error = "not authorized to run replSetGetStatus; the user needs the clusterMonitor role"
+		case errors.As(err, &cmdErr) && cmdErr.Code == 76:
+			result.Error = "server is not running as a replica set"
+		default:
+			result.Error = err.Error()
+		}
+		return result
+	}
+
+	result.ReplicaSet = status.Set
+	var primaryOptime time.Time
+	for _, m := range status.Members {
+		if m.StateStr == "PRIMARY" {
+			result.Primary = m.Name
+			primaryOptime = m.OptimeDate
+		}
+	}
+	for _, m := range status.Members {
+		member := MongoDBMemberStatus{
+			Name:       m.Name,
+			State:      m.StateStr,
+			Healthy:    m.Health == 1,
+			Self:       m.Self,
+			SyncSource: m.SyncSourceHost,
+			PingMs:     m.PingMs,
+		}
+		if !m.OptimeDate.IsZero() {
+			member.OptimeDate = m.OptimeDate.UTC().Format(time.RFC3339)
+			if !primaryOptime.IsZero() {
+				lag := primaryOptime.Sub(m.OptimeDate).Seconds()
+				member.LagSeconds = &lag
+			}
+		}
+		result.Members = append(result.Members, member)
+	}
+	return result
+}
+
+func mongodbReplicaSetHandler(w http.ResponseWriter, r *http.Request) {
+	uri := os.Getenv("MONGODB_URI")
+	if uri == "" {
+		writeCheckResult(w, false, MongoDBReplicaSetResult{Members: []MongoDBMemberStatus{}, Error: "MONGODB_URI is not set"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), checkTimeout(r.Context()))
+	defer cancel()
+	result := checkMongoDBReplicaSet(ctx, uri)
+	writeCheckResult(w, result.Error == "", result)
+}
@@ -0,0 +1,382 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+type MySQLCheckResult struct {
+	Connected     bool    `json:"connected"`
+	LatencyMs     float64 `json:"latency_ms"`
+	ServerVersion string  `json:"server_version,omitempty"`
+	ClientCert    bool    `json:"client_cert"`
+	TLSError      string  `json:"tls_error,omitempty"`
+	Hint          string  `json:"hint,omitempty"`
+	EgressIP      string  `json:"egress_ip,omitempty"`
+	Attempts      int     `json:"attempts,omitempty"`
+	Error         string  `json:"error,omitempty"`
+	ErrorCategory string  `json:"error_category,omitempty"`
+}
+
+// getMySQLURL returns MYSQL_URL, falling back to DATABASE_URL when it uses
+// the mysql scheme.
+func getMySQLURL() string {
+	if val := os.Getenv("MYSQL_URL"); val != "" {
+		return val
+	}
+	if val := os.Getenv("DATABASE_URL"); strings.HasPrefix(val, "mysql://") {
+		return val
+	}
+	return ""
+}
+
+// mysqlDSN converts a mysql:// URL (as provided by DigitalOcean) into the
+// DSN format expected by go-sql-driver/mysql. Values that are not URLs are
+// assumed to already be driver DSNs.
+func mysqlDSN(raw string) (string, error) {
+	if !strings.HasPrefix(raw, "mysql://") {
+		return raw, nil
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", err
+	}
+
+	cfg := mysql.NewConfig()
+	cfg.User = u.User.Username()
+	cfg.Passwd, _ = u.User.Password()
+	cfg.Net = "tcp"
+	cfg.Addr = u.Host
+	if u.Port() == "" {
+		cfg.Addr = u.Host + ":3306"
+	}
+	cfg.DBName = strings.TrimPrefix(u.Path, "/")
+
+	query := u.Query()
+	mode := query.Get("ssl-mode")
+	if mode == "" {
+		mode = query.Get("sslmode")
+	}
+	switch strings.ToLower(mode) {
+	case "", "disabled", "disable":
+	case "preferred", "prefer":
+		cfg.TLSConfig = "preferred"
+	case "required", "require":
+		cfg.TLSConfig = "skip-verify"
+	case "verify_ca", "verify-ca", "verify_identity", "verify-full":
+		cfg.TLSConfig = "true"
+	default:
+		return "", fmt.Errorf("unsupported ssl mode %q", mode)
+	}
+	return cfg.FormatDSN(), nil
+}
+
+// classifyTLSError distinguishes certificate verification failures from
+// other TLS handshake problems. It returns "" for non-TLS errors.
+func classifyTLSError(err error) string {
+	var certErr *tls.CertificateVerificationError
+	var authErr x509.UnknownAuthorityError
+	var hostErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+	switch {
+	case errors.As(err, &certErr), errors.As(err, &authErr),
+		errors.As(err, &hostErr), errors.As(err, &invalidErr):
+		return "certificate verification failed (set a CA certificate or use ssl-mode=REQUIRED)"
+	}
+	var headerErr tls.RecordHeaderError
+	if errors.As(err, &headerErr) || strings.Contains(err.Error(), "tls:") {
+		return "TLS handshake failed"
+	}
+	return ""
+}
+
+// openMySQL validates a MYSQL_URL value and opens a single-connection pool
+// for it, attaching the MYSQL_SSLCERT/MYSQL_SSLKEY client certificate when
+// the ssl-mode enables TLS. It reports whether the certificate was attached.
+func openMySQL(ctx context.Context, raw string) (*sql.DB, bool, error) {
+	// Native go-sql-driver DSNs (user:pass@tcp(host)/db) aren't URLs.
+	if strings.Contains(raw, "://") || !strings.Contains(raw, "/") || strings.Contains(raw, "${") {
+		if err := validateConnString(raw, "mysql"); err != nil {
+			return nil, false, err
+		}
+	}
+	dsn, err := mysqlDSN(raw)
+	if err != nil {
+		return nil, false, errors.New(redactConnStrings(err.Error()))
+	}
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return nil, false, errors.New(redactConnStrings(err.Error()))
+	}
+	cert, err := loadClientCert("MYSQL")
+	if err != nil {
+		return nil, false, err
+	}
+	clientCert := cert != nil && cfg.TLS != nil
+	if clientCert {
+		cfg.TLS.Certificates = []tls.Certificate{*cert}
+	}
+	if useCheckDialer(ctx) {
+		cfg.DialFunc = dialOutbound
+	}
+	connector, err := mysql.NewConnector(cfg)
+	if err != nil {
+		return nil, false, errors.New(redactConnStrings(err.Error()))
+	}
+	db := sql.OpenDB(connector)
+	db.SetMaxOpenConns(1)
+	return db, clientCert, nil
+}
+
+// checkMySQL connects to the server and reads its version.
+func checkMySQL(ctx context.Context, raw string) MySQLCheckResult {
+	var result MySQLCheckResult
+
+	db, clientCert, err := openMySQL(ctx, raw)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	defer trackClient("mysql", db.Close)()
+	result.ClientCert = clientCert
+
+	start := time.Now()
+	if err := db.QueryRowContext(ctx, "SELECT VERSION()").Scan(&result.ServerVersion); err != nil {
+		result.Error = err.Error()
+		result.ErrorCategory = errorCategory(err)
+		result.TLSError = classifyTLSError(err)
+		result.Hint, result.EgressIP = timeoutHint(err)
+		return result
+	}
+	result.LatencyMs = latencyMs(time.Since(start))
+	result.Connected = true
+	return result
+}
+
+func mysqlCheckHandler(w http.ResponseWriter, r *http.Request) {
+	raw := getMySQLURL()
+	if raw == "" {
+		writeCheckResult(w, false, MySQLCheckResult{Error: "MYSQL_URL is not set"})
+		return
+	}
+
+	result, attempts := retryCheck(r.Context(), func(ctx context.Context) (MySQLCheckResult, bool) {
+		result := checkMySQL(ctx, raw)
+		return result, result.Connected
+	})
+	result.Attempts = attempts
+	recordCheckResult("mysql", result.Connected)
+	writeCheckResult(w, result.Connected, result)
+}
+
+type MySQLConnectionUsage struct {
+	MaxConnections   int     `json:"max_connections"`
+	ThreadsConnected int     `json:"threads_connected"`
+	UsagePct         float64 `json:"usage_pct"`
+	NearLimit        bool    `json:"near_limit"`
+	Error            string  `json:"error,omitempty"`
+}
+
+type MySQLProcessList struct {
+	Total    int    `json:"total"`
+	Sleeping int    `json:"sleeping"`
+	Active   int    `json:"active"`
+	Note     string `json:"note,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+type MySQLReplicationStatus struct {
+	IsReplica           bool   `json:"is_replica"`
+	SourceHost          string `json:"source_host,omitempty"`
+	IORunning           string `json:"io_running,omitempty"`
+	SQLRunning          string `json:"sql_running,omitempty"`
+	SecondsBehindSource *int64 `json:"seconds_behind_source,omitempty"`
+	LastIOError         string `json:"last_io_error,omitempty"`
+	LastSQLError        string `json:"last_sql_error,omitempty"`
+	Error               string `json:"error,omitempty"`
+}
+
+// MySQLStatusResult groups the diagnostics behind /check/mysql/status. Each
+// section carries its own error so a missing privilege on one query still
+// leaves the others populated.
+type MySQLStatusResult struct {
+	Connected     bool                    `json:"connected"`
+	ServerVersion string                  `json:"server_version,omitempty"`
+	Connections   *MySQLConnectionUsage   `json:"connections,omitempty"`
+	Processes     *MySQLProcessList       `json:"processes,omitempty"`
+	Replication   *MySQLReplicationStatus `json:"replication,omitempty"`
+	Hint          string                  `json:"hint,omitempty"`
+	EgressIP      string                  `json:"egress_ip,omitempty"`
+	Error         string                  `json:"error,omitempty"`
+	ErrorCategory string                  `json:"error_category,omitempty"`
+}
+
+// queryRowMaps runs query and returns each row keyed by column name, with
+// NULLs as empty strings. SHOW statements vary their columns by server
+// version, so scanning by name is simpler than by position.
+func queryRowMaps(ctx context.Context, db *sql.DB, query string) ([]map[string]string, error) {
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	var results []map[string]string
+	for rows.Next() {
+		values := make([]sql.NullString, len(columns))
+		dest := make([]any, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		row := make(map[string]string, len(columns))
+		for i, column := range columns {
+			row[column] = values[i].String
+		}
+		results = append(results, row)
+	}
+	return results, rows.Err()
+}
+
+// mysqlConnectionUsage compares threads_connected with max_connections.
+func mysqlConnectionUsage(ctx context.Context, db *sql.DB) MySQLConnectionUsage {
+	var usage MySQLConnectionUsage
+	if err := db.QueryRowContext(ctx, "SELECT @@max_connections").Scan(&usage.MaxConnections); err != nil {
+		usage.Error = err.Error()
+		return usage
+	}
+	var name string
+	if err := db.QueryRowContext(ctx, "SHOW GLOBAL STATUS LIKE 'Threads_connected'").Scan(&name, &usage.ThreadsConnected); err != nil {
+		usage.Error = err.Error()
+		return usage
+	}
+	if usage.MaxConnections > 0 {
+		usage.UsagePct = float64(usage.ThreadsConnected) / float64(usage.MaxConnections) * 100
+		usage.NearLimit = usage.UsagePct > connectionWarnPct
+	}
+	return usage
+}
+
+// mysqlProcessList counts SHOW PROCESSLIST entries by whether they are
+// idle. Without the PROCESS privilege only the user's own threads appear.
+func mysqlProcessList(ctx context.Context, db *sql.DB) MySQLProcessList {
+	var list MySQLProcessList
+	rows, err := queryRowMaps(ctx, db, "SHOW PROCESSLIST")
+	if err != nil {
+		list.Error = err.Error()
+		return list
+	}
+	users := make(map[string]bool)
+	for _, row := range rows {
+		list.Total++
+		users[row["User"]] = true
+		if row["Command"] == "Sleep" {
+			list.Sleeping++
+		} else {
+			list.Active++
+		}
+	}
+	if len(users) <= 1 {
+		list.Note = "only threads visible to this user are counted; grant PROCESS to see all connections"
+	}
+	return list
+}
+
+// mysqlReplicationStatus reads SHOW REPLICA STATUS, falling back to SHOW
+// SLAVE STATUS on servers older than MySQL 8.0.22. No rows means the server
+// is not a replica.
+func mysqlReplicationStatus(ctx context.Context, db *sql.DB) MySQLReplicationStatus {
+	var status MySQLReplicationStatus
+	rows, err := queryRowMaps(ctx, db, "SHOW REPLICA STATUS")
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == 1064 { // ER_PARSE_ERROR
+		rows, err = queryRowMaps(ctx, db, "SHOW SLAVE STATUS")
+	}
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+	if len(rows) == 0 {
+		return status
+	}
+
+	// Column names use Source/Replica on newer servers and Master/Slave on
+	// older ones.
+	row := rows[0]
+	field := func(names ...string) string {
+		for _, name := range names {
+			if val, ok := row[name]; ok {
+				return val
+			}
+		}
+		return ""
+	}
+	status.IsReplica = true
+	status.SourceHost = field("Source_Host", "Master_Host")
+	status.IORunning = field("Replica_IO_Running", "Slave_IO_Running")
+	status.SQLRunning = field("Replica_SQL_Running", "Slave_SQL_Running")
+	status.LastIOError = field("Last_IO_Error")
+	status.LastSQLError = field("Last_SQL_Error")
+	// Seconds_Behind_Source is NULL while replication is stopped.
+	if lag, err := strconv.ParseInt(field("Seconds_Behind_Source", "Seconds_Behind_Master"), 10, 64); err == nil {
+		status.SecondsBehindSource = &lag
+	}
+	return status
+}
+
+// checkMySQLStatus reports connection usage, the process list and
+// replication state.
+func checkMySQLStatus(ctx context.Context, raw string) MySQLStatusResult {
+	var result MySQLStatusResult
+
+	db, _, err := openMySQL(ctx, raw)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	defer trackClient("mysql", db.Close)()
+
+	if err := db.QueryRowContext(ctx, "SELECT VERSION()").Scan(&result.ServerVersion); err != nil {
+		result.Error = err.Error()
+		result.ErrorCategory = errorCategory(err)
+		result.Hint, result.EgressIP = timeoutHint(err)
+		return result
+	}
+	result.Connected = true
+	connections := mysqlConnectionUsage(ctx, db)
+	processes := mysqlProcessList(ctx, db)
+	replication := mysqlReplicationStatus(ctx, db)
+	result.Connections, result.Processes, result.Replication = &connections, &processes, &replication
+	return result
+}
+
+func mysqlStatusHandler(w http.ResponseWriter, r *http.Request) {
+	raw := getMySQLURL()
+	if raw == "" {
+		writeCheckResult(w, false, MySQLStatusResult{Error: "MYSQL_URL is not set"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), checkTimeout(r.Context()))
+	defer cancel()
+	result := checkMySQLStatus(ctx, raw)
+	writeCheckResult(w, result.Connected, result)
+}
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type OpenSearchCheckResult struct {
+	Connected     bool    `json:"connected"`
+	ClusterName   string  `json:"cluster_name,omitempty"`
+	ClusterStatus string  `json:"cluster_status,omitempty"`
+	NumberOfNodes int     `json:"number_of_nodes"`
+	LatencyMs     float64 `json:"latency_ms"`
+	Hint          string  `json:"hint,omitempty"`
+	EgressIP      string  `json:"egress_ip,omitempty"`
+	Attempts      int     `json:"attempts,omitempty"`
+	Error         string  `json:"error,omitempty"`
+	ErrorCategory string  `json:"error_category,omitempty"`
+}
+
+// newOpenSearchRequest builds a request against the cluster at rawURL,
+// moving any credentials embedded in the URL into a basic auth header. path
+// may carry a query string; a body is sent as JSON.
+func newOpenSearchRequest(ctx context.Context, method, rawURL, path string, body io.Reader) (*http.Request, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	user := u.User
+	u.User = nil
+	path, query, _ := strings.Cut(path, "?")
+	u.Path = strings.TrimSuffix(u.Path, "/") + path
+	u.RawQuery = query
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if user != nil {
+		password, _ := user.Password()
+		req.SetBasicAuth(user.Username(), password)
+	}
+	return req, nil
+}
+
+// openSearchClient returns an HTTP client that skips certificate verification
+// only when INSECURE_TLS=true.
+func openSearchClient(ctx context.Context) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if os.Getenv("INSECURE_TLS") == "true" {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	if useCheckDialer(ctx) {
+		transport.DialContext = dialOutbound
+	}
+	return &http.Client{Transport: transport}
+}
+
+// checkOpenSearch queries the cluster health API.
+func checkOpenSearch(ctx context.Context, rawURL string) OpenSearchCheckResult {
+	var result OpenSearchCheckResult
+
+	if err := validateConnString(rawURL, "http"); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	req, err := newOpenSearchRequest(ctx, http.MethodGet, rawURL, "/_cluster/health", nil)
+	if err != nil {
+		result.Error = redactConnStrings(err.Error())
+		return result
+	}
+	client := openSearchClient(ctx)
+	defer client.CloseIdleConnections()
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		result.Error = err.Error()
+		result.ErrorCategory = errorCategory(err)
+		result.Hint, result.EgressIP = timeoutHint(err)
+		return result
+	}
+	defer resp.Body.Close()
+	result.LatencyMs = latencyMs(time.Since(start))
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		result.Error = fmt.Sprintf("cluster health returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+		result.ErrorCategory = errorCategoryUnknown
+		if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+			result.ErrorCategory = errorCategoryAuth
+		}
+		return result
+	}
+
+	var health struct {
+		ClusterName   string `json:"cluster_name"`
+		Status        string `json:"status"`
+		NumberOfNodes int    `json:"number_of_nodes"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&health); err != nil {
+		result.Error = "invalid cluster health response: " + err.Error()
+		return result
+	}
+	result.Connected = true
+	result.ClusterName = health.ClusterName
+	result.ClusterStatus = health.Status
+	result.NumberOfNodes = health.NumberOfNodes
+	return result
+}
+
+func opensearchCheckHandler(w http.ResponseWriter, r *http.Request) {
+	rawURL := os.Getenv("OPENSEARCH_URL")
+	if rawURL == "" {
+		writeCheckResult(w, false, OpenSearchCheckResult{Error: "OPENSEARCH_URL is not set"})
+		return
+	}
+
+	result, attempts := retryCheck(r.Context(), func(ctx context.Context) (OpenSearchCheckResult, bool) {
+		result := checkOpenSearch(ctx, rawURL)
+		return result, result.Connected
+	})
+	result.Attempts = attempts
+	recordCheckResult("opensearch", result.Connected)
+	writeCheckResult(w, result.Connected, result)
+}
+
+type OpenSearchIndex struct {
+	Name          string `json:"name"`
+	Health        string `json:"health"`
+	Status        string `json:"status"`
+	DocsCount     int64  `json:"docs_count"`
+	SizeBytes     int64  `json:"size_bytes"`
+	PrimaryShards int    `json:"primary_shards"`
+	Replicas      int    `json:"replicas"`
+}
+
+type OpenSearchTestQuery struct {
+	Index     string  `json:"index"`
+	Exists    bool    `json:"exists"`
+	Hits      int64   `json:"hits"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+type OpenSearchIndicesResult struct {
+	Indices       []OpenSearchIndex    `json:"indices"`
+	TestQuery     *OpenSearchTestQuery `json:"test_query,omitempty"`
+	Error         string               `json:"error,omitempty"`
+	ErrorCategory string               `json:"error_category,omitempty"`
+}
+
+// openSearchStatusError is a non-2xx response from the cluster.
+type openSearchStatusError struct {
+	StatusCode int
+	Status     string
+	Body       string
+}
+
+func (e *openSearchStatusError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Status, e.Body)
+}
+
+// openSearchDo sends req and decodes a 2xx JSON response into out. Other
+// statuses are returned as an *openSearchStatusError.
+func openSearchDo(client *http.Client, req *http.Request, out any) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return &openSearchStatusError{StatusCode: resp.StatusCode, Status: resp.Status, Body: strings.TrimSpace(string(body))}
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// openSearchErrorCategory is errorCategory with HTTP 401/403 treated as
+// credential failures.
+func openSearchErrorCategory(err error) string {
+	var statusErr *openSearchStatusError
+	if errors.As(err, &statusErr) {
+		if statusErr.StatusCode == http.StatusUnauthorized || statusErr.StatusCode == http.StatusForbidden {
+			return errorCategoryAuth
+		}
+		return errorCategoryUnknown
+	}
+	return errorCategory(err)
+}
+
+// checkOpenSearchIndices lists the cluster's indices and, when testIndex is
+// set, counts its documents with a match_all query.
+func checkOpenSearchIndices(ctx context.Context, rawURL, testIndex string) OpenSearchIndicesResult {
+	result := OpenSearchIndicesResult{Indices: []OpenSearchIndex{}}
+
+	if err := validateConnString(rawURL, "http"); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	req, err := newOpenSearchRequest(ctx, http.MethodGet, rawURL, "/_cat/indices?format=json&bytes=b", nil)
+	if err != nil {
+		result.Error = redactConnStrings(err.Error())
+		return result
+	}
+	client := openSearchClient(ctx)
+	defer client.CloseIdleConnections()
+
+	// _cat reports every number as a string, and null for closed indices.
+	var indices []struct {
+		Index     string `json:"index"`
+		Health    string `json:"health"`
+		Status    string `json:"status"`
+		DocsCount string `json:"docs.count"`
+		StoreSize string `json:"store.size"`
+		Pri       string `json:"pri"`
+		Rep       string `json:"rep"`
+	}
+	if err := openSearchDo(client, req, &indices); err != nil {
+		result.Error = "listing indices: " + err.Error()
+		result.ErrorCategory = openSearchErrorCategory(err)
+		return result
+	}
+	for _, index := range indices {
+		entry := OpenSearchIndex{Name: index.Index, Health: index.Health, Status: index.Status}
+		entry.DocsCount, _ = strconv.ParseInt(index.DocsCount, 10, 64)
+		entry.SizeBytes, _ = strconv.ParseInt(index.StoreSize, 10, 64)
+		entry.PrimaryShards, _ = strconv.Atoi(index.Pri)
+		entry.Replicas, _ = strconv.Atoi(index.Rep)
+		result.Indices = append(result.Indices, entry)
+	}
+	sort.Slice(result.Indices, func(i, j int) bool { return result.Indices[i].Name < result.Indices[j].Name })
+
+	if testIndex != "" {
+		result.TestQuery = runOpenSearchTestQuery(ctx, client, rawURL, testIndex)
+	}
+	return result
+}
+
+// runOpenSearchTestQuery counts every document in index.
+func runOpenSearchTestQuery(ctx context.Context, client *http.Client, rawURL, index string) *OpenSearchTestQuery {
+	query := &OpenSearchTestQuery{Index: index}
+	body := strings.NewReader(`{"size": 0, "track_total_hits": true, "query": {"match_all": {}}}`)
+	req, err := newOpenSearchRequest(ctx, http.MethodPost, rawURL, "/"+url.PathEscape(index)+"/_search", body)
+	if err != nil {
+		query.Error = redactConnStrings(err.Error())
+		return query
+	}
+
+	var response struct {
+		Hits struct {
+			Total struct {
+				Value int64 `json:"value"`
+			} `json:"total"`
+		} `json:"hits"`
+	}
+	start := time.Now()
+	err = openSearchDo(client, req, &response)
+	query.LatencyMs = latencyMs(time.Since(start))
+	var statusErr *openSearchStatusError
+	if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound {
+		query.Error = "index does not exist"
+		return query
+	}
+	if err != nil {
+		query.Error = err.Error()
+		return query
+	}
+	query.Exists = true
+	query.Hits = response.Hits.Total.Value
+	return query
+}
+
+func opensearchIndicesHandler(w http.ResponseWriter, r *http.Request) {
+	rawURL := os.Getenv("OPENSEARCH_URL")
+	if rawURL == "" {
+		writeCheckResult(w, false, OpenSearchIndicesResult{Indices: []OpenSearchIndex{}, Error: "OPENSEARCH_URL is not set"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), checkTimeout(r.Context()))
+	defer cancel()
+	result := checkOpenSearchIndices(ctx, rawURL, os.Getenv("OPENSEARCH_TEST_INDEX"))
+	ok := result.Error == "" && (result.TestQuery == nil || result.TestQuery.Error == "")
+	writeCheckResult(w, ok, result)
+}
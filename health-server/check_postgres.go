@@ -0,0 +1,656 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"database/sql"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type PostgresCheckResult struct {
+	Role                  string   `json:"role,omitempty"`
+	Connected             bool     `json:"connected"`
+	LatencyMs             float64  `json:"latency_ms"`
+	ServerVersion         string   `json:"server_version,omitempty"`
+	InRecovery            bool     `json:"in_recovery"`
+	ReplayLSN             string   `json:"replay_lsn,omitempty"`
+	ReplicationLagSeconds *float64 `json:"replication_lag_seconds,omitempty"`
+	ClientCert            bool     `json:"client_cert"`
+	Hint                  string   `json:"hint,omitempty"`
+	EgressIP              string   `json:"egress_ip,omitempty"`
+	Attempts              int      `json:"attempts,omitempty"`
+	Error                 string   `json:"error,omitempty"`
+	ErrorCategory         string   `json:"error_category,omitempty"`
+}
+
+// postgresTarget is one PostgreSQL connection string and the role it plays.
+type postgresTarget struct {
+	Role string
+	DSN  string
+}
+
+// getPostgresTargets collects DATABASE_URL (primary), DATABASE_URL_REPLICA
+// and any comma-separated DATABASE_URLS. mysql:// URLs are skipped since
+// DATABASE_URL may point at MySQL instead.
+func getPostgresTargets() []postgresTarget {
+	var targets []postgresTarget
+	add := func(role, dsn string) {
+		dsn = strings.TrimSpace(dsn)
+		if dsn != "" && !strings.HasPrefix(dsn, "mysql://") {
+			targets = append(targets, postgresTarget{Role: role, DSN: dsn})
+		}
+	}
+	add("primary", os.Getenv("DATABASE_URL"))
+	add("replica", os.Getenv("DATABASE_URL_REPLICA"))
+	if urls := os.Getenv("DATABASE_URLS"); urls != "" {
+		for i, dsn := range strings.Split(urls, ",") {
+			add(fmt.Sprintf("database_urls[%d]", i), dsn)
+		}
+	}
+	return targets
+}
+
+// checkPostgres connects to dsn, runs SELECT 1 and reads the server version.
+// For standbys it also reports the replayed WAL position and replication lag.
+func checkPostgres(ctx context.Context, dsn string) PostgresCheckResult {
+	var result PostgresCheckResult
+
+	// lib/pq also accepts "host=... dbname=..." DSNs, which aren't URLs.
+	if strings.Contains(dsn, "://") || !strings.Contains(dsn, "=") {
+		if err := validateConnString(dsn, "postgres"); err != nil {
+			result.Error = err.Error()
+			return result
+		}
+	}
+	dsn, clientCert, err := withPostgresClientCert(dsn)
+	if err != nil {
+		result.Error = redactConnStrings(err.Error())
+		return result
+	}
+	result.ClientCert = clientCert
+	db, err := postgresDB(ctx, dsn)
+	if err != nil {
+		result.Error = redactConnStrings(err.Error())
+		return result
+	}
+	defer trackClient("postgres", db.Close)()
+	db.SetMaxOpenConns(1)
+
+	start := time.Now()
+	var one int
+	if err := db.QueryRowContext(ctx, "SELECT 1").Scan(&one); err != nil {
+		result.Error = err.Error()
+		result.ErrorCategory = errorCategory(err)
+		result.Hint, result.EgressIP = timeoutHint(err)
+		return result
+	}
+	result.LatencyMs = latencyMs(time.Since(start))
+	result.Connected = true
+
+	if err := db.QueryRowContext(ctx, "SHOW server_version").Scan(&result.ServerVersion); err != nil {
+		result.Error = "connected, but failed to read server version: " + err.Error()
+		return result
+	}
+
+	if err := db.QueryRowContext(ctx, "SELECT pg_is_in_recovery()").Scan(&result.InRecovery); err != nil || !result.InRecovery {
+		return result
+	}
+	var lsn sql.NullString
+	var lag sql.NullFloat64
+	err = db.QueryRowContext(ctx, `
+		SELECT pg_last_wal_replay_lsn()::text,
+		       EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp())`).Scan(&lsn, &lag)
+	if err != nil {
+		result.Error = "connected, but failed to read replication status: " + err.Error()
+		return result
+	}
+	result.ReplayLSN = lsn.String
+	if lag.Valid {
+		result.ReplicationLagSeconds = &lag.Float64
+	}
+	return result
+}
+
+// checkPostgresTargets checks every configured PostgreSQL target
+// concurrently, preserving their configured order.
+func checkPostgresTargets(ctx context.Context, targets []postgresTarget) ([]PostgresCheckResult, bool) {
+	results := make([]PostgresCheckResult, len(targets))
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		wg.Add(1)
+		go func(i int, target postgresTarget) {
+			defer wg.Done()
+			results[i] = checkPostgres(ctx, target.DSN)
+			results[i].Role = target.Role
+		}(i, target)
+	}
+	wg.Wait()
+
+	allOK := true
+	for _, result := range results {
+		if !result.Connected {
+			allOK = false
+		}
+	}
+	return results, allOK
+}
+
+// postgresCheckHandler returns one result per configured PostgreSQL
+// connection string, labeled by role.
+func postgresCheckHandler(w http.ResponseWriter, r *http.Request) {
+	targets := getPostgresTargets()
+	if len(targets) == 0 {
+		writeCheckResult(w, false, []PostgresCheckResult{{Error: "DATABASE_URL is not set"}})
+		return
+	}
+
+	results, attempts := retryCheck(r.Context(), func(ctx context.Context) ([]PostgresCheckResult, bool) {
+		return checkPostgresTargets(ctx, targets)
+	})
+	ok := true
+	for i := range results {
+		results[i].Attempts = attempts
+		ok = ok && results[i].Connected
+	}
+	recordCheckResult("postgres", ok)
+	writeCheckResult(w, ok, results)
+}
+
+// connectionWarnPct is the share of max_connections above which
+// /check/postgres/pool flags the server as near its limit.
+const connectionWarnPct = 80
+
+type PostgresPoolResult struct {
+	TotalConnections  int     `json:"total_connections"`
+	MaxConnections    int     `json:"max_connections"`
+	UsagePct          float64 `json:"usage_pct"`
+	ActiveConnections int     `json:"active_connections"`
+	IdleConnections   int     `json:"idle_connections"`
+	IdleInTransaction int     `json:"idle_in_transaction"`
+	NearLimit         bool    `json:"near_limit"`
+	Error             string  `json:"error,omitempty"`
+}
+
+// postgresDB opens dsn with lib/pq, dialing with dialOutbound when a SOCKS5
+// proxy is configured or ctx asks for one IP version.
+func postgresDB(ctx context.Context, dsn string) (*sql.DB, error) {
+	if !useCheckDialer(ctx) {
+		return sql.Open("postgres", dsn)
+	}
+	connector, err := pq.NewConnector(dsn)
+	if err != nil {
+		return nil, err
+	}
+	connector.Dialer(checkDialer{ipVersion: ipVersion(ctx)})
+	return sql.OpenDB(connector), nil
+}
+
+// openPostgres validates dsn and opens a single-connection pool for it with
+// the PG_SSLCERT/PG_SSLKEY client certificate applied.
+func openPostgres(ctx context.Context, dsn string) (*sql.DB, error) {
+	if strings.Contains(dsn, "://") || !strings.Contains(dsn, "=") {
+		if err := validateConnString(dsn, "postgres"); err != nil {
+			return nil, err
+		}
+	}
+	dsn, _, err := withPostgresClientCert(dsn)
+	if err != nil {
+		return nil, errors.New(redactConnStrings(err.Error()))
+	}
+	db, err := postgresDB(ctx, dsn)
+	if err != nil {
+		return nil, errors.New(redactConnStrings(err.Error()))
+	}
+	db.SetMaxOpenConns(1)
+	return db, nil
+}
+
+// checkPostgresPool reports server-wide connection usage from
+// pg_stat_activity, the usual culprit behind "too many connections".
+func checkPostgresPool(ctx context.Context, dsn string) PostgresPoolResult {
+	var result PostgresPoolResult
+
+	db, err := openPostgres(ctx, dsn)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	defer trackClient("postgres", db.Close)()
+
+	if err := db.QueryRowContext(ctx, "SELECT setting::int FROM pg_settings WHERE name = 'max_connections'").Scan(&result.MaxConnections); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	err = db.QueryRowContext(ctx, `
+		SELECT count(*),
+		       count(*) FILTER (WHERE state = 'active'),
+		       count(*) FILTER (WHERE state = 'idle'),
+		       count(*) FILTER (WHERE state LIKE 'idle in transaction%')
+		FROM pg_stat_activity
+		WHERE backend_type = 'client backend'`).Scan(
+		&result.TotalConnections,
+		&result.ActiveConnections,
+		&result.IdleConnections,
+		&result.IdleInTransaction,
+	)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	if result.MaxConnections > 0 {
+		result.UsagePct = float64(result.TotalConnections) / float64(result.MaxConnections) * 100
+		result.NearLimit = result.UsagePct > connectionWarnPct
+	}
+	return result
+}
+
+func postgresPoolHandler(w http.ResponseWriter, r *http.Request) {
+	dsn := os.Getenv("DATABASE_URL")
+	if dsn == "" {
+		writeCheckResult(w, false, PostgresPoolResult{Error: "DATABASE_URL is not set"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), checkTimeout(r.Context()))
+	defer cancel()
+	result := checkPostgresPool(ctx, dsn)
+	writeCheckResult(w, result.Error == "", result)
+}
+
+const (
+	defaultLockRows = 50
+	maxLockRows     = 500
+	// maxLockQueryLen truncates query text in /check/postgres/locks.
+	maxLockQueryLen = 1000
+)
+
+type PostgresLockWait struct {
+	BlockedPID          int     `json:"blocked_pid"`
+	BlockedUser         string  `json:"blocked_user"`
+	BlockedApplication  string  `json:"blocked_application,omitempty"`
+	BlockedQuery        string  `json:"blocked_query"`
+	WaitSeconds         float64 `json:"wait_seconds"`
+	LockType            string  `json:"lock_type"`
+	LockMode            string  `json:"lock_mode"`
+	Relation            string  `json:"relation,omitempty"`
+	BlockingPID         int     `json:"blocking_pid"`
+	BlockingUser        string  `json:"blocking_user"`
+	BlockingState       string  `json:"blocking_state"`
+	BlockingQuery       string  `json:"blocking_query"`
+	BlockingXactSeconds float64 `json:"blocking_transaction_seconds"`
+}
+
+type PostgresLocksResult struct {
+	Blocked       []PostgresLockWait `json:"blocked"`
+	Count         int                `json:"count"`
+	Truncated     bool               `json:"truncated,omitempty"`
+	Note          string             `json:"note,omitempty"`
+	Error         string             `json:"error,omitempty"`
+	ErrorCategory string             `json:"error_category,omitempty"`
+}
+
+// postgresLocksQuery pairs every ungranted lock with each backend blocking
+// it, longest wait first. $1 limits the rows; one extra is fetched to detect
+// truncation.
+const postgresLocksQuery = `
+	SELECT blocked.pid,
+	       coalesce(blocked.usename, ''),
+	       coalesce(blocked.application_name, ''),
+	       coalesce(blocked.query, ''),
+	       coalesce(extract(epoch FROM now() - blocked.query_start), 0)::float8,
+	       l.locktype,
+	       l.mode,
+	       coalesce(l.relation::regclass::text, ''),
+	       blocking.pid,
+	       coalesce(blocking.usename, ''),
+	       coalesce(blocking.state, ''),
+	       coalesce(blocking.query, ''),
+	       coalesce(extract(epoch FROM now() - blocking.xact_start), 0)::float8
+	FROM pg_locks l
+	JOIN pg_stat_activity blocked ON blocked.pid = l.pid
+	CROSS JOIN LATERAL unnest(pg_blocking_pids(l.pid)) AS b(pid)
+	JOIN pg_stat_activity blocking ON blocking.pid = b.pid
+	WHERE NOT l.granted
+	ORDER BY blocked.query_start, blocked.pid
+	LIMIT $1`
+
+var (
+	sqlStringLiteral  = regexp.MustCompile(`'(?:[^']|'')*'`)
+	sqlNumericLiteral = regexp.MustCompile(`\$?\b\d+(?:\.\d+)?\b`)
+)
+
+// redactSQLLiterals replaces string and numeric literals with '?' so query
+// text can be shared without the values it carried. $n placeholders stay.
+func redactSQLLiterals(query string) string {
+	query = sqlStringLiteral.ReplaceAllString(query, "?")
+	return sqlNumericLiteral.ReplaceAllStringFunc(query, func(literal string) string {
+		if strings.HasPrefix(literal, "$") {
+			return literal
+		}
+		return "?"
+	})
+}
+
+// lockQueryText truncates query text and, when redact is set, strips its
+// literals.
+func lockQueryText(query string, redact bool) string {
+	if redact {
+		query = redactSQLLiterals(query)
+	}
+	if len(query) > maxLockQueryLen {
+		query = query[:maxLockQueryLen] + "..."
+	}
+	return query
+}
+
+// checkPostgresLocks reports queries waiting on locks and the backends
+// holding them, from pg_locks joined with pg_stat_activity.
+func checkPostgresLocks(ctx context.Context, dsn string, limit int, redact bool) PostgresLocksResult {
+	result := PostgresLocksResult{Blocked: []PostgresLockWait{}}
+
+	db, err := openPostgres(ctx, dsn)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	defer trackClient("postgres", db.Close)()
+
+	rows, err := db.QueryContext(ctx, postgresLocksQuery, limit+1)
+	if err != nil {
+		result.Error = err.Error()
+		result.ErrorCategory = errorCategory(err)
+		return result
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var wait PostgresLockWait
+		if err := rows.Scan(
+			&wait.BlockedPID, &wait.BlockedUser, &wait.BlockedApplication, &wait.BlockedQuery, &wait.WaitSeconds,
+			&wait.LockType, &wait.LockMode, &wait.Relation,
+			&wait.BlockingPID, &wait.BlockingUser, &wait.BlockingState, &wait.BlockingQuery, &wait.BlockingXactSeconds,
+		); err != nil {
+			result.Error = err.Error()
+			return result
+		}
+		if len(result.Blocked) == limit {
+			result.Truncated = true
+			break
+		}
+		wait.BlockedQuery = lockQueryText(wait.BlockedQuery, redact)
+		wait.BlockingQuery = lockQueryText(wait.BlockingQuery, redact)
+		wait.WaitSeconds = math.Round(wait.WaitSeconds*1000) / 1000
+		wait.BlockingXactSeconds = math.Round(wait.BlockingXactSeconds*1000) / 1000
+		result.Blocked = append(result.Blocked, wait)
+	}
+	if err := rows.Err(); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Count = len(result.Blocked)
+	for _, wait := range result.Blocked {
+		if wait.BlockingQuery == "<insufficient privilege>" || wait.BlockedQuery == "<insufficient privilege>" {
+			result.Note = "other users' queries are hidden; grant pg_read_all_stats to see them"
+			break
+		}
+	}
+	return result
+}
+
+// postgresLocksHandler serves /check/postgres/locks[?limit=50&redact=true].
+func postgresLocksHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	limit := defaultLockRows
+	if val := query.Get("limit"); val != "" {
+		n, err := strconv.Atoi(val)
+		if err != nil || n < 1 || n > maxLockRows {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("limit must be a number between 1 and %d", maxLockRows))
+			return
+		}
+		limit = n
+	}
+	redact := query.Get("redact") == "true"
+
+	dsn := os.Getenv("DATABASE_URL")
+	if dsn == "" {
+		writeCheckResult(w, false, PostgresLocksResult{Blocked: []PostgresLockWait{}, Error: "DATABASE_URL is not set"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), checkTimeout(r.Context()))
+	defer cancel()
+	result := checkPostgresLocks(ctx, dsn, limit, redact)
+	writeCheckResult(w, result.Error == "", result)
+}
+
+// postgresSSLRequestCode is the SSLRequest message code from the PostgreSQL
+// wire protocol, sent in place of a startup message to ask for TLS.
+const postgresSSLRequestCode = 80877103
+
+type PostgresSSLResult struct {
+	Host              string            `json:"host"`
+	Port              string            `json:"port"`
+	SSLMode           string            `json:"sslmode,omitempty"`
+	SSLAccepted       bool              `json:"ssl_accepted"`
+	TLSVersion        string            `json:"tls_version,omitempty"`
+	CipherSuite       string            `json:"cipher_suite,omitempty"`
+	RootCert          string            `json:"root_cert"`
+	CAVerified        bool              `json:"ca_verified"`
+	HostnameVerified  bool              `json:"hostname_verified"`
+	ClientCert        bool              `json:"client_cert"`
+	VerificationError string            `json:"verification_error,omitempty"`
+	Certificates      []CertificateInfo `json:"certificates"`
+	Note              string            `json:"note,omitempty"`
+	Hint              string            `json:"hint,omitempty"`
+	EgressIP          string            `json:"egress_ip,omitempty"`
+	Error             string            `json:"error,omitempty"`
+	ErrorCategory     string            `json:"error_category,omitempty"`
+}
+
+// postgresDSNParams pulls host, port, sslmode and sslrootcert out of either
+// a postgres:// URL or a key=value DSN.
+func postgresDSNParams(dsn string) (map[string]string, error) {
+	params := map[string]string{"port": "5432"}
+	if strings.Contains(dsn, "://") {
+		u, err := url.Parse(dsn)
+		if err != nil {
+			return nil, err
+		}
+		params["host"] = u.Hostname()
+		if port := u.Port(); port != "" {
+			params["port"] = port
+		}
+		for key, values := range u.Query() {
+			params[key] = values[0]
+		}
+		return params, nil
+	}
+	for _, field := range strings.Fields(dsn) {
+		if key, value, ok := strings.Cut(field, "="); ok {
+			params[key] = strings.Trim(value, "'")
+		}
+	}
+	return params, nil
+}
+
+// loadRootCert reads a PEM bundle of CA certificates.
+func loadRootCert(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no PEM certificates found in %s", path)
+	}
+	return pool, nil
+}
+
+// checkPostgresSSL performs the PostgreSQL SSLRequest handshake by hand so it
+// can report what was negotiated, then verifies the server certificate the
+// way sslmode=verify-ca and verify-full would. The root certificate comes
+// from PGSSLROOTCERT, then sslrootcert in the DSN, then the system roots.
+func checkPostgresSSL(ctx context.Context, dsn string) PostgresSSLResult {
+	result := PostgresSSLResult{Certificates: []CertificateInfo{}}
+
+	if strings.Contains(dsn, "://") || !strings.Contains(dsn, "=") {
+		if err := validateConnString(dsn, "postgres"); err != nil {
+			result.Error = err.Error()
+			return result
+		}
+	}
+	params, err := postgresDSNParams(dsn)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Host, result.Port, result.SSLMode = params["host"], params["port"], params["sslmode"]
+	if result.Host == "" {
+		result.Error = "connection string has no host"
+		return result
+	}
+
+	var roots *x509.CertPool
+	result.RootCert = "system"
+	rootCertPath := os.Getenv("PGSSLROOTCERT")
+	if rootCertPath == "" {
+		rootCertPath = params["sslrootcert"]
+	}
+	if rootCertPath != "" {
+		roots, err = loadRootCert(rootCertPath)
+		if err != nil {
+			result.Error = "loading root certificate: " + err.Error()
+			return result
+		}
+		result.RootCert = rootCertPath
+	}
+
+	tlsConfig := &tls.Config{ServerName: result.Host, InsecureSkipVerify: true}
+	cert, err := loadClientCert("PG")
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	if cert != nil {
+		tlsConfig.Certificates = []tls.Certificate{*cert}
+		result.ClientCert = true
+	}
+
+	conn, err := dialOutbound(ctx, "tcp", net.JoinHostPort(result.Host, result.Port))
+	if err != nil {
+		result.Error = err.Error()
+		result.ErrorCategory = errorCategory(err)
+		result.Hint, result.EgressIP = timeoutHint(err)
+		return result
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	request := make([]byte, 8)
+	binary.BigEndian.PutUint32(request[0:4], 8)
+	binary.BigEndian.PutUint32(request[4:8], postgresSSLRequestCode)
+	if _, err := conn.Write(request); err != nil {
+		result.Error = err.Error()
+		result.ErrorCategory = errorCategory(err)
+		return result
+	}
+	reply := make([]byte, 1)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		result.Error = "reading SSLRequest response: " + err.Error()
+		result.ErrorCategory = errorCategory(err)
+		return result
+	}
+	if reply[0] != 'S' {
+		result.Error = "server does not accept SSL connections"
+		result.ErrorCategory = errorCategoryTLS
+		return result
+	}
+	result.SSLAccepted = true
+
+	tlsConn := tls.Client(conn, tlsConfig)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		result.Error = "TLS handshake failed: " + err.Error()
+		result.ErrorCategory = errorCategoryTLS
+		if isTimeoutError(err) {
+			result.ErrorCategory = errorCategoryTimeout
+		}
+		return result
+	}
+	state := tlsConn.ConnectionState()
+	result.TLSVersion = tls.VersionName(state.Version)
+	result.CipherSuite = tls.CipherSuiteName(state.CipherSuite)
+	for _, cert := range state.PeerCertificates {
+		result.Certificates = append(result.Certificates, describeCertificate(cert))
+	}
+	if len(state.PeerCertificates) == 0 {
+		result.VerificationError = "server presented no certificate"
+		return result
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range state.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+	opts := x509.VerifyOptions{Roots: roots, Intermediates: intermediates}
+	if _, err := state.PeerCertificates[0].Verify(opts); err != nil {
+		result.VerificationError = err.Error()
+	} else {
+		result.CAVerified = true
+		opts.DNSName = result.Host
+		if _, err := state.PeerCertificates[0].Verify(opts); err != nil {
+			result.VerificationError = err.Error()
+		} else {
+			result.HostnameVerified = true
+		}
+	}
+
+	switch {
+	case !result.CAVerified && rootCertPath == "":
+		result.Note = "certificate not trusted by the system roots; set PGSSLROOTCERT to the cluster's CA certificate to test verify-ca/verify-full"
+	case !result.CAVerified:
+		result.Note = "sslmode=require would connect, but verify-ca and verify-full would fail"
+	case !result.HostnameVerified:
+		result.Note = "sslmode=verify-ca would connect, but verify-full would fail on the hostname"
+	}
+	return result
+}
+
+func postgresSSLHandler(w http.ResponseWriter, r *http.Request) {
+	dsn := os.Getenv("DATABASE_URL")
+	if dsn == "" {
+		writeCheckResult(w, false, PostgresSSLResult{Certificates: []CertificateInfo{}, Error: "DATABASE_URL is not set"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), checkTimeout(r.Context()))
+	defer cancel()
+	result := checkPostgresSSL(ctx, dsn)
+	// A verification failure only matters if the configured sslmode would
+	// have enforced it.
+	ok := result.Error == "" && result.TLSVersion != ""
+	switch result.SSLMode {
+	case "verify-ca":
+		ok = ok && result.CAVerified
+	case "verify-full":
+		ok = ok && result.HostnameVerified
+	}
+	writeCheckResult(w, ok, result)
+}
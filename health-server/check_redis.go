@@ -0,0 +1,506 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type RedisCheckResult struct {
+	Connected     bool    `json:"connected"`
+	LatencyMs     float64 `json:"latency_ms"`
+	Mode          string  `json:"mode,omitempty"`
+	Server        string  `json:"server,omitempty"`
+	ServerVersion string  `json:"server_version,omitempty"`
+	ClientCert    bool    `json:"client_cert"`
+	Hint          string  `json:"hint,omitempty"`
+	EgressIP      string  `json:"egress_ip,omitempty"`
+	Attempts      int     `json:"attempts,omitempty"`
+	Error         string  `json:"error,omitempty"`
+	ErrorCategory string  `json:"error_category,omitempty"`
+}
+
+type RedisInfoResult struct {
+	Server           string            `json:"server,omitempty"`
+	ServerVersion    string            `json:"server_version,omitempty"`
+	Role             string            `json:"role,omitempty"`
+	UsedMemory       *int64            `json:"used_memory,omitempty"`
+	UsedMemoryHuman  string            `json:"used_memory_human,omitempty"`
+	MaxMemory        *int64            `json:"maxmemory,omitempty"`
+	MaxMemoryPolicy  string            `json:"maxmemory_policy,omitempty"`
+	ConnectedClients *int64            `json:"connected_clients,omitempty"`
+	EvictedKeys      *int64            `json:"evicted_keys,omitempty"`
+	Fields           map[string]string `json:"fields,omitempty"`
+	Error            string            `json:"error,omitempty"`
+}
+
+// parseRedisInfo turns the "key:value" lines of an INFO reply into a map.
+func parseRedisInfo(info string) map[string]string {
+	fields := make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(info))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if key, val, ok := strings.Cut(line, ":"); ok {
+			fields[key] = val
+		}
+	}
+	return fields
+}
+
+// infoInt reads a numeric INFO field, returning nil when the server doesn't
+// report it.
+func infoInt(fields map[string]string, key string) *int64 {
+	n, err := strconv.ParseInt(fields[key], 10, 64)
+	if err != nil {
+		return nil
+	}
+	return &n
+}
+
+// redisOptions parses a redis:// or rediss:// URL into client options for a
+// one-off check, adding the REDIS_SSLCERT/REDIS_SSLKEY client certificate
+// to TLS connections.
+func redisOptions(ctx context.Context, rawURL string) (*redis.Options, error) {
+	opts, err := redis.ParseURL(rawURL)
+	if err != nil {
+		return nil, errors.New(redactConnStrings(err.Error()))
+	}
+	opts.MaxRetries = -1
+	// Let the check's deadline (CHECK_TIMEOUT or ?timeout=) bound reads
+	// instead of go-redis's fixed 3s.
+	opts.ContextTimeoutEnabled = true
+	cert, err := loadClientCert("REDIS")
+	if err != nil {
+		return nil, err
+	}
+	if cert != nil && opts.TLSConfig != nil {
+		opts.TLSConfig.Certificates = []tls.Certificate{*cert}
+	}
+	if useCheckDialer(ctx) {
+		// A custom dialer replaces go-redis's own, which does the TLS.
+		tlsConfig := opts.TLSConfig
+		dialer := checkDialer{ipVersion: ipVersion(ctx)}
+		opts.Dialer = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			conn, err := dialer.DialContext(ctx, network, addr)
+			if err != nil || tlsConfig == nil {
+				return conn, err
+			}
+			return tls.Client(conn, tlsConfig), nil
+		}
+	}
+	return opts, nil
+}
+
+// checkRedis PINGs the server at rawURL and identifies its mode. Valkey
+// reports itself through server_name/valkey_version but is otherwise treated
+// exactly like Redis.
+func checkRedis(ctx context.Context, rawURL string) RedisCheckResult {
+	var result RedisCheckResult
+
+	if err := validateConnString(rawURL, "redis"); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	opts, err := redisOptions(ctx, rawURL)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.ClientCert = opts.TLSConfig != nil && len(opts.TLSConfig.Certificates) > 0
+	client := redis.NewClient(opts)
+	defer trackClient("redis", client.Close)()
+
+	start := time.Now()
+	if err := client.Ping(ctx).Err(); err != nil {
+		result.Error = err.Error()
+		result.ErrorCategory = errorCategory(err)
+		result.Hint, result.EgressIP = timeoutHint(err)
+		return result
+	}
+	result.LatencyMs = latencyMs(time.Since(start))
+	result.Connected = true
+
+	// INFO may be disabled via rename-command; mode detection is best effort.
+	info, err := client.Info(ctx, "server").Result()
+	if err != nil {
+		return result
+	}
+	fields := parseRedisInfo(info)
+	result.Mode = fields["redis_mode"]
+	result.Server = "redis"
+	result.ServerVersion = fields["redis_version"]
+	if v, ok := fields["valkey_version"]; ok {
+		result.Server = "valkey"
+		result.ServerVersion = v
+	} else if fields["server_name"] != "" {
+		result.Server = fields["server_name"]
+	}
+	return result
+}
+
+func redisCheckHandler(w http.ResponseWriter, r *http.Request) {
+	rawURL := os.Getenv("REDIS_URL")
+	if rawURL == "" {
+		writeCheckResult(w, false, RedisCheckResult{Error: "REDIS_URL is not set"})
+		return
+	}
+
+	result, attempts := retryCheck(r.Context(), func(ctx context.Context) (RedisCheckResult, bool) {
+		result := checkRedis(ctx, rawURL)
+		return result, result.Connected
+	})
+	result.Attempts = attempts
+	recordCheckResult("redis", result.Connected)
+	writeCheckResult(w, result.Connected, result)
+}
+
+// checkRedisInfo runs INFO and extracts the memory, client and eviction stats
+// that usually explain a slow cache. Fields Valkey doesn't report are omitted.
+func checkRedisInfo(ctx context.Context, rawURL string) RedisInfoResult {
+	var result RedisInfoResult
+
+	if err := validateConnString(rawURL, "redis"); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	opts, err := redisOptions(ctx, rawURL)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	client := redis.NewClient(opts)
+	defer trackClient("redis", client.Close)()
+
+	info, err := client.Info(ctx).Result()
+	if err != nil {
+		result.Error = err.Error()
+		if strings.Contains(strings.ToLower(result.Error), "unknown command") {
+			result.Error = "INFO is disabled on this server: " + result.Error
+		}
+		return result
+	}
+	fields := parseRedisInfo(info)
+	result.Fields = fields
+	result.Server = "redis"
+	result.ServerVersion = fields["redis_version"]
+	if v, ok := fields["valkey_version"]; ok {
+		result.Server = "valkey"
+		result.ServerVersion = v
+	} else if fields["server_name"] != "" {
+		result.Server = fields["server_name"]
+	}
+	result.Role = fields["role"]
+	result.UsedMemory = infoInt(fields, "used_memory")
+	result.UsedMemoryHuman = fields["used_memory_human"]
+	result.MaxMemory = infoInt(fields, "maxmemory")
+	result.MaxMemoryPolicy = fields["maxmemory_policy"]
+	result.ConnectedClients = infoInt(fields, "connected_clients")
+	result.EvictedKeys = infoInt(fields, "evicted_keys")
+	return result
+}
+
+func redisInfoHandler(w http.ResponseWriter, r *http.Request) {
+	rawURL := os.Getenv("REDIS_URL")
+	if rawURL == "" {
+		writeCheckResult(w, false, RedisInfoResult{Error: "REDIS_URL is not set"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), checkTimeout(r.Context()))
+	defer cancel()
+	result := checkRedisInfo(ctx, rawURL)
+	writeCheckResult(w, result.Error == "", result)
+}
+
+type RedisClusterNode struct {
+	ID        string   `json:"id"`
+	Address   string   `json:"address"`
+	Role      string   `json:"role"`
+	MasterID  string   `json:"master_id,omitempty"`
+	Flags     []string `json:"flags"`
+	LinkState string   `json:"link_state"`
+	Slots     []string `json:"slots,omitempty"`
+	SlotCount int      `json:"slot_count"`
+	Failed    bool     `json:"failed"`
+}
+
+type RedisClusterResult struct {
+	Cluster       bool               `json:"cluster"`
+	State         string             `json:"state,omitempty"`
+	SlotsAssigned *int64             `json:"slots_assigned,omitempty"`
+	SlotsOK       *int64             `json:"slots_ok,omitempty"`
+	SlotsPFail    *int64             `json:"slots_pfail,omitempty"`
+	SlotsFail     *int64             `json:"slots_fail,omitempty"`
+	KnownNodes    *int64             `json:"known_nodes,omitempty"`
+	Masters       int                `json:"masters"`
+	Replicas      int                `json:"replicas"`
+	FailedNodes   []string           `json:"failed_nodes"`
+	Nodes         []RedisClusterNode `json:"nodes"`
+	Note          string             `json:"note,omitempty"`
+	Error         string             `json:"error,omitempty"`
+}
+
+// parseClusterNodes parses CLUSTER NODES output, one node per line:
+// <id> <ip:port@cport> <flags> <master> <ping> <pong> <epoch> <link> <slot>...
+func parseClusterNodes(raw string) []RedisClusterNode {
+	nodes := []RedisClusterNode{}
+	for _, line := range strings.Split(strings.TrimSpace(raw), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 8 {
+			continue
+		}
+		node := RedisClusterNode{
+			ID:        fields[0],
+			Address:   strings.SplitN(fields[1], "@", 2)[0],
+			Flags:     strings.Split(fields[2], ","),
+			LinkState: fields[7],
+		}
+		if fields[3] != "-" {
+			node.MasterID = fields[3]
+		}
+		for _, flag := range node.Flags {
+			switch flag {
+			case "master":
+				node.Role = "master"
+			case "slave", "replica":
+				node.Role = "replica"
+			case "fail", "fail?":
+				node.Failed = true
+			}
+		}
+		for _, slot := range fields[8:] {
+			// Entries in brackets are slots being migrated or imported.
+			if strings.HasPrefix(slot, "[") {
+				continue
+			}
+			node.Slots = append(node.Slots, slot)
+			start, end, isRange := strings.Cut(slot, "-")
+			if !isRange {
+				node.SlotCount++
+				continue
+			}
+			from, err1 := strconv.Atoi(start)
+			to, err2 := strconv.Atoi(end)
+			if err1 == nil && err2 == nil && to >= from {
+				node.SlotCount += to - from + 1
+			}
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
+// checkRedisCluster runs CLUSTER INFO and CLUSTER NODES to report slot
+// coverage, masters and replicas, and any node in fail state. A server
+// without cluster mode is reported as such rather than as an error.
+func checkRedisCluster(ctx context.Context, rawURL string) RedisClusterResult {
+	result := RedisClusterResult{FailedNodes: []string{}, Nodes: []RedisClusterNode{}}
+
+	if err := validateConnString(rawURL, "redis"); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	opts, err := redisOptions(ctx, rawURL)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	client := redis.NewClient(opts)
+	defer trackClient("redis", client.Close)()
+
+	info, err := client.ClusterInfo(ctx).Result()
+	if err != nil {
+		if strings.Contains(strings.ToLower(err.Error()), "cluster support disabled") {
+			result.Note = "not a cluster: cluster mode is disabled on this server"
+			return result
+		}
+		result.Error = err.Error()
+		return result
+	}
+	result.Cluster = true
+	fields := parseRedisInfo(info)
+	result.State = fields["cluster_state"]
+	result.SlotsAssigned = infoInt(fields, "cluster_slots_assigned")
+	result.SlotsOK = infoInt(fields, "cluster_slots_ok")
+	result.SlotsPFail = infoInt(fields, "cluster_slots_pfail")
+	result.SlotsFail = infoInt(fields, "cluster_slots_fail")
+	result.KnownNodes = infoInt(fields, "cluster_known_nodes")
+
+	nodes, err := client.ClusterNodes(ctx).Result()
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Nodes = parseClusterNodes(nodes)
+	for _, node := range result.Nodes {
+		switch node.Role {
+		case "master":
+			result.Masters++
+		case "replica":
+			result.Replicas++
+		}
+		if node.Failed {
+			result.FailedNodes = append(result.FailedNodes, node.Address)
+		}
+	}
+	return result
+}
+
+func redisClusterHandler(w http.ResponseWriter, r *http.Request) {
+	rawURL := os.Getenv("REDIS_URL")
+	if rawURL == "" {
+		writeCheckResult(w, false, RedisClusterResult{FailedNodes: []string{}, Nodes: []RedisClusterNode{}, Error: "REDIS_URL is not set"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), checkTimeout(r.Context()))
+	defer cancel()
+	result := checkRedisCluster(ctx, rawURL)
+	ok := result.Error == "" && (!result.Cluster || (result.State == "ok" && len(result.FailedNodes) == 0))
+	writeCheckResult(w, ok, result)
+}
+
+type RedisLatencySample struct {
+	Timestamp string `json:"timestamp"`
+	LatencyMs int64  `json:"latency_ms"`
+}
+
+type RedisLatencyEvent struct {
+	Event       string               `json:"event"`
+	Description string               `json:"description,omitempty"`
+	LatestAt    string               `json:"latest_at"`
+	LatestMs    int64                `json:"latest_ms"`
+	MaxMs       int64                `json:"max_ms"`
+	History     []RedisLatencySample `json:"history"`
+}
+
+type RedisLatencyResult struct {
+	ThresholdMs *int64              `json:"threshold_ms,omitempty"`
+	Events      []RedisLatencyEvent `json:"events"`
+	Note        string              `json:"note,omitempty"`
+	Error       string              `json:"error,omitempty"`
+}
+
+// redisLatencyEvents explains the latency monitor's most common event names.
+var redisLatencyEvents = map[string]string{
+	"command":                 "a slow command, usually O(N) such as KEYS, SMEMBERS or a large DEL",
+	"fast-command":            "an O(1) or O(log N) command that was slow, which points at the host rather than the workload",
+	"fork":                    "the fork() for an RDB snapshot or AOF rewrite",
+	"expire-cycle":            "the active expiry cycle removing many expired keys at once",
+	"eviction-cycle":          "evicting keys to stay under maxmemory",
+	"eviction-del":            "deleting a large key during eviction",
+	"aof-fsync-always":        "fsync with appendfsync always",
+	"aof-write":               "writing to the AOF",
+	"aof-write-pending-fsync": "an AOF write waiting on a pending fsync",
+	"aof-write-active-child":  "an AOF write while a child process was saving",
+	"aof-write-alone":         "an AOF write with no fsync or child in progress",
+	"aof-fstat":               "fstat on the AOF",
+	"aof-rename":              "renaming the rewritten AOF into place",
+	"aof-rewrite-diff-write":  "writing the AOF rewrite buffer",
+	"rdb-unlink-temp-file":    "unlinking a temporary RDB file",
+}
+
+// checkRedisLatency reports the spikes the latency monitor recorded with
+// LATENCY LATEST, and the LATENCY HISTORY of each event. An empty report is
+// explained using latency-monitor-threshold when CONFIG is available, since
+// with monitoring off (threshold 0) nothing is ever recorded.
+func checkRedisLatency(ctx context.Context, rawURL string) RedisLatencyResult {
+	result := RedisLatencyResult{Events: []RedisLatencyEvent{}}
+
+	if err := validateConnString(rawURL, "redis"); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	opts, err := redisOptions(ctx, rawURL)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	client := redis.NewClient(opts)
+	defer trackClient("redis", client.Close)()
+
+	if config, err := client.ConfigGet(ctx, "latency-monitor-threshold").Result(); err == nil {
+		if val, ok := config["latency-monitor-threshold"]; ok {
+			if threshold, err := strconv.ParseInt(val, 10, 64); err == nil {
+				result.ThresholdMs = &threshold
+			}
+		}
+	}
+
+	latest, err := client.Latency(ctx).Result()
+	if err != nil {
+		result.Error = err.Error()
+		if strings.Contains(strings.ToLower(result.Error), "unknown command") {
+			result.Error = "LATENCY is disabled on this server: " + result.Error
+		}
+		return result
+	}
+	for _, entry := range latest {
+		event := RedisLatencyEvent{
+			Event:       entry.Name,
+			Description: redisLatencyEvents[entry.Name],
+			LatestAt:    entry.Time.UTC().Format(time.RFC3339),
+			LatestMs:    entry.Latest.Milliseconds(),
+			MaxMs:       entry.Max.Milliseconds(),
+			History:     []RedisLatencySample{},
+		}
+		history, err := client.Do(ctx, "LATENCY", "HISTORY", entry.Name).Slice()
+		if err != nil {
+			result.Error = err.Error()
+			return result
+		}
+		for _, item := range history {
+			sample, ok := item.([]interface{})
+			if !ok || len(sample) < 2 {
+				continue
+			}
+			ts, ok1 := sample[0].(int64)
+			ms, ok2 := sample[1].(int64)
+			if ok1 && ok2 {
+				event.History = append(event.History, RedisLatencySample{
+					Timestamp: time.Unix(ts, 0).UTC().Format(time.RFC3339),
+					LatencyMs: ms,
+				})
+			}
+		}
+		result.Events = append(result.Events, event)
+	}
+
+	if len(result.Events) == 0 {
+		switch {
+		case result.ThresholdMs == nil:
+			result.Note = "no latency spikes recorded, but CONFIG is unavailable so it is unknown whether latency monitoring is on (latency-monitor-threshold above 0)"
+		case *result.ThresholdMs == 0:
+			result.Note = "latency monitoring is disabled (latency-monitor-threshold is 0); enable it with CONFIG SET latency-monitor-threshold 100 to record events slower than 100ms"
+		default:
+			result.Note = fmt.Sprintf("no event slower than latency-monitor-threshold (%dms) has been recorded", *result.ThresholdMs)
+		}
+	}
+	return result
+}
+
+func redisLatencyHandler(w http.ResponseWriter, r *http.Request) {
+	rawURL := os.Getenv("REDIS_URL")
+	if rawURL == "" {
+		writeCheckResult(w, false, RedisLatencyResult{Events: []RedisLatencyEvent{}, Error: "REDIS_URL is not set"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), checkTimeout(r.Context()))
+	defer cancel()
+	result := checkRedisLatency(ctx, rawURL)
+	writeCheckResult(w, result.Error == "", result)
+}
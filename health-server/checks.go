@@ -0,0 +1,395 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// checkTimeoutKey carries a per-request ?timeout= override in a context.
+type checkTimeoutKey struct{}
+
+// checkTimeout bounds a single dependency check: the ?timeout= the /check/*
+// request asked for, or CHECK_TIMEOUT (default 5s).
+func checkTimeout(ctx context.Context) time.Duration {
+	if timeout, ok := ctx.Value(checkTimeoutKey{}).(time.Duration); ok {
+		return timeout
+	}
+	return getEnvDuration("CHECK_TIMEOUT", 5*time.Second)
+}
+
+// withCheckTimeout lets a /check/* request override CHECK_TIMEOUT with
+// ?timeout=, read and capped by timeoutParam like /tcp's, answering 400 for
+// an invalid value.
+func withCheckTimeout(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("timeout") == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		timeout, err := timeoutParam(r, 0)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		ctx := context.WithValue(r.Context(), checkTimeoutKey{}, timeout)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// ipVersionKey carries a per-request ?ipversion= in a context.
+type ipVersionKey struct{}
+
+// ipVersion returns the IP version ctx restricts connections and lookups
+// to, "4" or "6", or "" for either.
+func ipVersion(ctx context.Context) string {
+	version, _ := ctx.Value(ipVersionKey{}).(string)
+	return version
+}
+
+// dialNetwork narrows a "tcp" or "udp" network to the IP version ctx asks
+// for, e.g. "tcp4".
+func dialNetwork(ctx context.Context, network string) string {
+	if version := ipVersion(ctx); version != "" && (network == "tcp" || network == "udp") {
+		return network + version
+	}
+	return network
+}
+
+// withIPVersion lets a request force IPv4 or IPv6 with ?ipversion=4|6, to
+// tell which path works when a host resolves to both, answering 400 for
+// anything else.
+func withIPVersion(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		val := r.URL.Query().Get("ipversion")
+		switch val {
+		case "":
+			next.ServeHTTP(w, r)
+		case "4", "6":
+			ctx := context.WithValue(r.Context(), ipVersionKey{}, val)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		default:
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid ipversion %q: must be 4 or 6", val))
+		}
+	})
+}
+
+// checkRetries reads CHECK_RETRIES, the number of attempts a /check/*
+// handler makes before reporting failure (default 1), and
+// CHECK_RETRY_BACKOFF, the delay before the first retry (default 500ms),
+// which doubles after each attempt.
+func checkRetries() (int, time.Duration) {
+	attempts := 1
+	if val := os.Getenv("CHECK_RETRIES"); val != "" {
+		if n, err := strconv.Atoi(val); err == nil && n >= 1 {
+			attempts = n
+		} else {
+			slog.Warn("invalid CHECK_RETRIES, using default", "value", val, "default", attempts)
+		}
+	}
+	return attempts, getEnvDuration("CHECK_RETRY_BACKOFF", 500*time.Millisecond)
+}
+
+// retryCheck runs check until it succeeds or CHECK_RETRIES attempts have
+// been made, giving each attempt its own CHECK_TIMEOUT and backing off
+// exponentially in between. It returns the last result and the number of
+// attempts made.
+func retryCheck[T any](ctx context.Context, check func(ctx context.Context) (T, bool)) (T, int) {
+	attempts, backoff := checkRetries()
+	for attempt := 1; ; attempt++ {
+		attemptCtx, cancel := context.WithTimeout(ctx, checkTimeout(ctx))
+		result, ok := check(attemptCtx)
+		cancel()
+		if ok || attempt >= attempts {
+			return result, attempt
+		}
+		select {
+		case <-ctx.Done():
+			return result, attempt
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// latencyMs converts an elapsed duration to fractional milliseconds.
+func latencyMs(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// trustedSourcesHint explains the usual cause of a database connection
+// timeout on App Platform.
+const trustedSourcesHint = "connection timed out — check the database trusted sources / firewall rules"
+
+// isTimeoutError reports whether err looks like a connection that never got
+// an answer, as opposed to one that was refused or rejected.
+func isTimeoutError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "timeout") || strings.Contains(msg, "timed out") || strings.Contains(msg, "deadline exceeded")
+}
+
+// Categories reported in error_category so monitoring can branch on the
+// kind of failure without matching error messages.
+const (
+	errorCategoryDNS     = "dns"
+	errorCategoryTimeout = "timeout"
+	errorCategoryRefused = "connection_refused"
+	errorCategoryAuth    = "auth"
+	errorCategoryTLS     = "tls"
+	errorCategoryUnknown = "unknown"
+)
+
+// authErrorMarkers are lowercased fragments of the credential errors the
+// database drivers return: PostgreSQL, MySQL 1045, Redis
+// WRONGPASS/NOAUTH, MongoDB, Kafka SASL and HTTP 401/403.
+var authErrorMarkers = []string{
+	"authentication failed",
+	"password authentication",
+	"no pg_hba.conf entry",
+	"access denied",
+	"wrongpass",
+	"noauth",
+	"auth failed",
+	"sasl",
+	"unauthorized",
+	"forbidden",
+}
+
+// errorCategory classifies a failed check's error. It returns "" for nil.
+func errorCategory(err error) string {
+	if err == nil {
+		return ""
+	}
+	msg := strings.ToLower(err.Error())
+	var dnsErr *net.DNSError
+	// "no suitable address" means the host has no address of the
+	// ?ipversion= family.
+	if errors.As(err, &dnsErr) || strings.Contains(msg, "no such host") || strings.Contains(msg, "server misbehaving") ||
+		strings.Contains(msg, "no suitable address") {
+		return errorCategoryDNS
+	}
+	if isTimeoutError(err) {
+		return errorCategoryTimeout
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) || strings.Contains(msg, "connection refused") {
+		return errorCategoryRefused
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code.Class() == "28" { // invalid authorization
+		return errorCategoryAuth
+	}
+	for _, marker := range authErrorMarkers {
+		if strings.Contains(msg, marker) {
+			return errorCategoryAuth
+		}
+	}
+	if classifyTLSError(err) != "" || strings.Contains(msg, "x509:") || strings.Contains(msg, "certificate") {
+		return errorCategoryTLS
+	}
+	return errorCategoryUnknown
+}
+
+// timeoutHint returns the trusted-sources hint and the container's egress IP
+// when err is a timeout, and empty strings otherwise. Only an egress IP
+// already found is reported, so a failed check isn't slowed down further;
+// when there is none, or it's stale, a lookup starts in the background for
+// the next failure.
+func timeoutHint(err error) (hint string, egressIP string) {
+	if !isTimeoutError(err) {
+		return "", ""
+	}
+	egressIP, _, fresh := egress.cached()
+	if !fresh {
+		egress.refresh()
+	}
+	return trustedSourcesHint, egressIP
+}
+
+// writeCheckResult encodes a check result, answering 503 when the
+// dependency could not be reached.
+func writeCheckResult(w http.ResponseWriter, ok bool, result interface{}) {
+	status := http.StatusOK
+	if !ok {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, result)
+}
+
+// dbCheck ties a database check to the environment that configures it.
+// EnvVar is the variable a config file's databases entry sets.
+type dbCheck struct {
+	Name       string
+	EnvVar     string
+	Configured func() bool
+	Run        func(ctx context.Context) (result interface{}, ok bool)
+}
+
+var dbChecks = []dbCheck{
+	{
+		Name:       "postgres",
+		EnvVar:     "DATABASE_URL",
+		Configured: func() bool { return len(getPostgresTargets()) > 0 },
+		Run: func(ctx context.Context) (interface{}, bool) {
+			return checkPostgresTargets(ctx, getPostgresTargets())
+		},
+	},
+	{
+		Name:       "redis",
+		EnvVar:     "REDIS_URL",
+		Configured: func() bool { return os.Getenv("REDIS_URL") != "" },
+		Run: func(ctx context.Context) (interface{}, bool) {
+			result := checkRedis(ctx, os.Getenv("REDIS_URL"))
+			return result, result.Connected
+		},
+	},
+	{
+		Name:       "mysql",
+		EnvVar:     "MYSQL_URL",
+		Configured: func() bool { return getMySQLURL() != "" },
+		Run: func(ctx context.Context) (interface{}, bool) {
+			result := checkMySQL(ctx, getMySQLURL())
+			return result, result.Connected
+		},
+	},
+	{
+		Name:       "mongodb",
+		EnvVar:     "MONGODB_URI",
+		Configured: func() bool { return os.Getenv("MONGODB_URI") != "" },
+		Run: func(ctx context.Context) (interface{}, bool) {
+			result := checkMongoDB(ctx, os.Getenv("MONGODB_URI"))
+			return result, result.Connected
+		},
+	},
+	{
+		Name:       "kafka",
+		EnvVar:     "KAFKA_BROKERS",
+		Configured: func() bool { return len(getKafkaBrokers()) > 0 },
+		Run: func(ctx context.Context) (interface{}, bool) {
+			result := checkKafka(ctx, getKafkaBrokers())
+			return result, len(result.ReachableBrokers) > 0 && len(result.UnreachableBrokers) == 0
+		},
+	},
+	{
+		Name:       "opensearch",
+		EnvVar:     "OPENSEARCH_URL",
+		Configured: func() bool { return os.Getenv("OPENSEARCH_URL") != "" },
+		Run: func(ctx context.Context) (interface{}, bool) {
+			result := checkOpenSearch(ctx, os.Getenv("OPENSEARCH_URL"))
+			return result, result.Connected
+		},
+	},
+}
+
+// checkOutcome is the result of one dependency check, as returned by
+// /check/all and stored by the background poller.
+type checkOutcome struct {
+	OK          bool        `json:"ok"`
+	LastChecked string      `json:"last_checked"`
+	Error       string      `json:"error,omitempty"`
+	Result      interface{} `json:"result"`
+}
+
+// checkResultError extracts the error message from a check result.
+func checkResultError(result interface{}) string {
+	switch r := result.(type) {
+	case []PostgresCheckResult:
+		var errs []string
+		for _, result := range r {
+			if result.Error != "" {
+				errs = append(errs, result.Role+": "+result.Error)
+			}
+		}
+		return strings.Join(errs, "; ")
+	case PostgresCheckResult:
+		return r.Error
+	case RedisCheckResult:
+		return r.Error
+	case MySQLCheckResult:
+		return r.Error
+	case MongoDBCheckResult:
+		return r.Error
+	case OpenSearchCheckResult:
+		return r.Error
+	case KafkaCheckResult:
+		if r.Error == "" && len(r.UnreachableBrokers) > 0 {
+			return fmt.Sprintf("%d of %d brokers unreachable", len(r.UnreachableBrokers), len(r.UnreachableBrokers)+len(r.ReachableBrokers))
+		}
+		return r.Error
+	}
+	return ""
+}
+
+// runAllChecks runs every configured check concurrently under a single
+// deadline and reports whether all of them passed.
+func runAllChecks(ctx context.Context) (map[string]checkOutcome, bool) {
+	results := make(map[string]checkOutcome)
+	allOK := true
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, check := range dbChecks {
+		if !check.Configured() {
+			continue
+		}
+		wg.Add(1)
+		go func(check dbCheck) {
+			defer wg.Done()
+			result, ok := check.Run(ctx)
+			recordCheckResult(check.Name, ok)
+			updateDependencyHealth(check.Name, ok)
+			outcome := checkOutcome{
+				OK:          ok,
+				LastChecked: time.Now().UTC().Format(time.RFC3339),
+				Result:      result,
+			}
+			if !ok {
+				outcome.Error = checkResultError(result)
+			}
+			mu.Lock()
+			results[check.Name] = outcome
+			if !ok {
+				allOK = false
+			}
+			mu.Unlock()
+		}(check)
+	}
+	wg.Wait()
+	return results, allOK
+}
+
+// allChecksHandler serves the poller's cached results. Live checks run when
+// polling is disabled, before the first poll finishes, or with ?live=true.
+func allChecksHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("live") != "true" {
+		if results, ok, populated := checkCache.snapshot(); populated {
+			writeCheckResult(w, ok, results)
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), checkTimeout(r.Context()))
+	defer cancel()
+	results, ok := runAllChecks(ctx)
+	writeCheckResult(w, ok, results)
+}
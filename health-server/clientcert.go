@@ -0,0 +1,110 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// readPEMSetting returns the PEM held by env var name, which is either the
+// PEM itself (escaped newlines allowed, as App Platform env vars are single
+// line) or a path to a PEM file.
+func readPEMSetting(name string) ([]byte, error) {
+	val := os.Getenv(name)
+	if strings.Contains(val, "-----BEGIN") {
+		return []byte(strings.ReplaceAll(val, `\n`, "\n")), nil
+	}
+	data, err := os.ReadFile(val)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", name, err)
+	}
+	return data, nil
+}
+
+// clientCertPEM reads the client certificate and key for mutual TLS from
+// <prefix>_SSLCERT and <prefix>_SSLKEY. It returns nil PEMs when neither is
+// set, and fails if only one is or they don't form a key pair.
+func clientCertPEM(prefix string) (certPEM, keyPEM []byte, err error) {
+	certVar, keyVar := prefix+"_SSLCERT", prefix+"_SSLKEY"
+	if os.Getenv(certVar) == "" && os.Getenv(keyVar) == "" {
+		return nil, nil, nil
+	}
+	if os.Getenv(certVar) == "" || os.Getenv(keyVar) == "" {
+		return nil, nil, fmt.Errorf("%s and %s must both be set", certVar, keyVar)
+	}
+	if certPEM, err = readPEMSetting(certVar); err != nil {
+		return nil, nil, err
+	}
+	if keyPEM, err = readPEMSetting(keyVar); err != nil {
+		return nil, nil, err
+	}
+	if _, err := tls.X509KeyPair(certPEM, keyPEM); err != nil {
+		return nil, nil, fmt.Errorf("invalid %s/%s: %w", certVar, keyVar, err)
+	}
+	return certPEM, keyPEM, nil
+}
+
+// loadClientCert is clientCertPEM parsed into a certificate for a
+// tls.Config. It returns nil when no client certificate is configured.
+func loadClientCert(prefix string) (*tls.Certificate, error) {
+	certPEM, keyPEM, err := clientCertPEM(prefix)
+	if err != nil || certPEM == nil {
+		return nil, err
+	}
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, err
+	}
+	return &cert, nil
+}
+
+// withPostgresClientCert adds PG_SSLCERT/PG_SSLKEY to dsn as inline sslcert
+// and sslkey settings, and reports whether it did. With sslinline lib/pq
+// reads every certificate setting as PEM, so a root certificate given as a
+// path (sslrootcert in the DSN, else PGSSLROOTCERT) is inlined as well.
+func withPostgresClientCert(dsn string) (string, bool, error) {
+	certPEM, keyPEM, err := clientCertPEM("PG")
+	if err != nil || certPEM == nil {
+		return dsn, false, err
+	}
+	params, err := postgresDSNParams(dsn)
+	if err != nil {
+		return "", false, err
+	}
+	settings := [][2]string{
+		{"sslinline", "true"},
+		{"sslcert", string(certPEM)},
+		{"sslkey", string(keyPEM)},
+	}
+	rootCert := params["sslrootcert"]
+	if rootCert == "" {
+		rootCert = os.Getenv("PGSSLROOTCERT")
+	}
+	if rootCert != "" && rootCert != "system" {
+		data, err := os.ReadFile(rootCert)
+		if err != nil {
+			return "", false, fmt.Errorf("reading sslrootcert: %w", err)
+		}
+		settings = append(settings, [2]string{"sslrootcert", string(data)})
+	}
+
+	if strings.Contains(dsn, "://") {
+		u, err := url.Parse(dsn)
+		if err != nil {
+			return "", false, err
+		}
+		query := u.Query()
+		for _, setting := range settings {
+			query.Set(setting[0], setting[1])
+		}
+		u.RawQuery = query.Encode()
+		return u.String(), true, nil
+	}
+	quote := strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+	for _, setting := range settings {
+		dsn += fmt.Sprintf(" %s='%s'", setting[0], quote.Replace(setting[1]))
+	}
+	return dsn, true, nil
+}
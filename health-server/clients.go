@@ -0,0 +1,66 @@
+package main
+
+import (
+	"log/slog"
+	"sync"
+)
+
+// openClients holds the database clients checks currently have open. Each
+// check opens its own client and releases it when done, so this is normally
+// empty; at shutdown it holds the clients of checks the poller or a slow
+// request is still running, which closeClients disconnects cleanly instead
+// of letting the process exit drop them.
+var openClients = struct {
+	sync.Mutex
+	next    int
+	closers map[int]trackedClient
+}{closers: make(map[int]trackedClient)}
+
+type trackedClient struct {
+	kind  string
+	close func() error
+}
+
+// trackClient registers the close function of a newly opened client and
+// returns the release function the check defers, which closes the client
+// and forgets it. The client is closed once, by whichever of release and
+// closeClients runs first.
+func trackClient(kind string, close func() error) func() {
+	var once sync.Once
+	closeOnce := func() error {
+		var err error
+		once.Do(func() { err = close() })
+		return err
+	}
+
+	openClients.Lock()
+	id := openClients.next
+	openClients.next++
+	openClients.closers[id] = trackedClient{kind: kind, close: closeOnce}
+	openClients.Unlock()
+
+	return func() {
+		openClients.Lock()
+		delete(openClients.closers, id)
+		openClients.Unlock()
+		closeOnce()
+	}
+}
+
+// closeClients closes every client still open. main calls it once the
+// server has shut down.
+func closeClients() {
+	openClients.Lock()
+	clients := openClients.closers
+	openClients.closers = make(map[int]trackedClient)
+	openClients.Unlock()
+
+	for _, client := range clients {
+		if err := client.close(); err != nil {
+			slog.Warn("closing database client", "kind", client.kind, "error", err)
+		}
+	}
+	if len(clients) > 0 {
+		slog.Info("closed database clients still open at shutdown", "count", len(clients))
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+)
+
+// ntpEpochOffset is the number of seconds between the NTP epoch (1900) and
+// the Unix epoch (1970).
+const ntpEpochOffset = 2208988800
+
+type ClockResult struct {
+	SystemTime  string  `json:"system_time"`
+	NTPServer   string  `json:"ntp_server"`
+	NTPTime     string  `json:"ntp_time,omitempty"`
+	OffsetMs    float64 `json:"offset_ms"`
+	RoundTripMs float64 `json:"round_trip_ms"`
+	Stratum     int     `json:"stratum,omitempty"`
+	ThresholdMs float64 `json:"threshold_ms"`
+	Skewed      bool    `json:"skewed"`
+	Error       string  `json:"error,omitempty"`
+}
+
+// ntpTime decodes a 64-bit NTP timestamp.
+func ntpTime(b []byte) time.Time {
+	secs := int64(binary.BigEndian.Uint32(b[0:4])) - ntpEpochOffset
+	frac := int64(binary.BigEndian.Uint32(b[4:8]))
+	return time.Unix(secs, frac*1e9>>32)
+}
+
+// queryNTP sends a single SNTP request to server and returns the local
+// clock's offset from it and the round-trip delay, using the usual
+// four-timestamp calculation.
+func queryNTP(ctx context.Context, server string) (offset, delay time.Duration, serverTime time.Time, stratum int, err error) {
+	if _, _, splitErr := net.SplitHostPort(server); splitErr != nil {
+		server = net.JoinHostPort(server, "123")
+	}
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "udp", server)
+	if err != nil {
+		return 0, 0, time.Time{}, 0, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	request := make([]byte, 48)
+	request[0] = 0x23 // LI 0, version 4, mode 3 (client)
+	sent := time.Now()
+	if _, err := conn.Write(request); err != nil {
+		return 0, 0, time.Time{}, 0, err
+	}
+	response := make([]byte, 48)
+	n, err := conn.Read(response)
+	received := time.Now()
+	if err != nil {
+		return 0, 0, time.Time{}, 0, err
+	}
+	if n < 48 || response[0]&0x07 != 4 {
+		return 0, 0, time.Time{}, 0, fmt.Errorf("invalid NTP response from %s", server)
+	}
+	stratum = int(response[1])
+	if stratum == 0 {
+		return 0, 0, time.Time{}, 0, fmt.Errorf("NTP server %s sent a kiss-of-death (%s)", server, response[12:16])
+	}
+
+	serverReceived := ntpTime(response[32:40])
+	serverSent := ntpTime(response[40:48])
+	offset = (serverReceived.Sub(sent) + serverSent.Sub(received)) / 2
+	delay = received.Sub(sent) - serverSent.Sub(serverReceived)
+	return offset, delay, received.Add(offset), stratum, nil
+}
+
+// clockHandler compares the system clock against NTP_SERVER (default
+// pool.ntp.org) and flags an offset beyond CLOCK_SKEW_THRESHOLD (default
+// 1s), a common cause of TLS and token expiry failures.
+func clockHandler(w http.ResponseWriter, r *http.Request) {
+	server := os.Getenv("NTP_SERVER")
+	if server == "" {
+		server = "pool.ntp.org"
+	}
+	threshold := getEnvDuration("CLOCK_SKEW_THRESHOLD", time.Second)
+	result := ClockResult{
+		SystemTime:  time.Now().UTC().Format(time.RFC3339Nano),
+		NTPServer:   server,
+		ThresholdMs: latencyMs(threshold),
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), checkTimeout(r.Context()))
+	defer cancel()
+	offset, delay, serverTime, stratum, err := queryNTP(ctx, server)
+	if err != nil {
+		result.Error = err.Error()
+		writeCheckResult(w, false, result)
+		return
+	}
+	result.NTPTime = serverTime.UTC().Format(time.RFC3339Nano)
+	result.OffsetMs = latencyMs(offset)
+	result.RoundTripMs = latencyMs(delay)
+	result.Stratum = stratum
+	result.Skewed = offset.Abs() > threshold
+	writeCheckResult(w, !result.Skewed, result)
+}
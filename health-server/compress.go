@@ -0,0 +1,158 @@
+package main
+
+import (
+	"compress/gzip"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// compressMinBytes is the smallest response body worth gzipping.
+const compressMinBytes = 1024
+
+var gzipWriters = sync.Pool{
+	New: func() any { return gzip.NewWriter(nil) },
+}
+
+// acceptsGzip reports whether the client listed gzip in Accept-Encoding
+// without disabling it with q=0.
+func acceptsGzip(r *http.Request) bool {
+	for _, header := range r.Header.Values("Accept-Encoding") {
+		for _, coding := range strings.Split(header, ",") {
+			name, params, _ := strings.Cut(coding, ";")
+			if !strings.EqualFold(strings.TrimSpace(name), "gzip") {
+				continue
+			}
+			if val, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+				q, err := strconv.ParseFloat(val, 64)
+				return err == nil && q > 0
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// gzipResponseWriter holds back the first compressMinBytes of a response
+// to decide whether it's worth compressing. A handler that flushes before
+// then (such as /exec streaming script output) gets an uncompressed
+// response, so its output isn't delayed.
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	status  int
+	buf     []byte
+	gz      *gzip.Writer
+	started bool
+}
+
+func (g *gzipResponseWriter) WriteHeader(status int) {
+	if g.started {
+		g.ResponseWriter.WriteHeader(status)
+		return
+	}
+	g.status = status
+}
+
+func (g *gzipResponseWriter) Write(p []byte) (int, error) {
+	if !g.started {
+		g.buf = append(g.buf, p...)
+		if len(g.buf) < compressMinBytes {
+			return len(p), nil
+		}
+		if err := g.start(true); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+	if g.gz != nil {
+		return g.gz.Write(p)
+	}
+	return g.ResponseWriter.Write(p)
+}
+
+// start sends the headers and any buffered body, compressing from here on
+// when compress is set and the response allows it.
+func (g *gzipResponseWriter) start(compress bool) error {
+	g.started = true
+	status := g.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	header := g.Header()
+	if compress && header.Get("Content-Encoding") == "" && status != http.StatusNoContent && status != http.StatusNotModified {
+		if header.Get("Content-Type") == "" {
+			header.Set("Content-Type", http.DetectContentType(g.buf))
+		}
+		header.Set("Content-Encoding", "gzip")
+		header.Del("Content-Length")
+		g.gz = gzipWriters.Get().(*gzip.Writer)
+		g.gz.Reset(g.ResponseWriter)
+	}
+	g.ResponseWriter.WriteHeader(status)
+
+	buf := g.buf
+	g.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	var err error
+	if g.gz != nil {
+		_, err = g.gz.Write(buf)
+	} else {
+		_, err = g.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+// Flush sends everything written so far to the client.
+func (g *gzipResponseWriter) Flush() {
+	if !g.started {
+		g.start(false)
+	}
+	if g.gz != nil {
+		g.gz.Flush()
+	}
+	http.NewResponseController(g.ResponseWriter).Flush()
+}
+
+// close finishes the response once the handler returns.
+func (g *gzipResponseWriter) close() {
+	if !g.started {
+		if g.status == 0 && len(g.buf) == 0 {
+			// Nothing was written; let net/http send its default response.
+			return
+		}
+		g.start(false)
+	}
+	if g.gz != nil {
+		g.gz.Close()
+		gzipWriters.Put(g.gz)
+		g.gz = nil
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (g *gzipResponseWriter) Unwrap() http.ResponseWriter {
+	return g.ResponseWriter
+}
+
+// compressResponses gzips response bodies of at least compressMinBytes for
+// clients that accept it. COMPRESSION=false disables it. WebSocket upgrades
+// and responses the handler already encoded (/metrics) pass through.
+func compressResponses(next http.Handler) http.Handler {
+	if os.Getenv("COMPRESSION") == "false" {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		if r.Method == http.MethodHead || r.Header.Get("Upgrade") != "" || !acceptsGzip(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		gw := &gzipResponseWriter{ResponseWriter: w}
+		defer gw.close()
+		next.ServeHTTP(gw, r)
+	})
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// fileConfig is the layout of the file named by CONFIG_FILE. YAML is a
+// superset of JSON, so either format parses.
+type fileConfig struct {
+	Port      string            `yaml:"port"`
+	BindAddr  string            `yaml:"bind_addr"`
+	Checks    []string          `yaml:"checks"`
+	AuthToken string            `yaml:"auth_token"`
+	LogLevel  string            `yaml:"log_level"`
+	Databases map[string]string `yaml:"databases"`
+	Env       map[string]string `yaml:"env"`
+}
+
+// environment flattens the config into the env vars it stands in for.
+// databases is keyed by dependency name (postgres, redis, ...) and env holds
+// any other variable verbatim.
+func (c fileConfig) environment() (map[string]string, error) {
+	vars := make(map[string]string)
+	for key, value := range c.Env {
+		vars[key] = value
+	}
+	set := func(key, value string) {
+		if value != "" {
+			vars[key] = value
+		}
+	}
+	set("PORT", c.Port)
+	set("BIND_ADDR", c.BindAddr)
+	set("READINESS_CHECKS", strings.Join(c.Checks, ","))
+	set("AUTH_TOKEN", c.AuthToken)
+	set("LOG_LEVEL", c.LogLevel)
+
+	for name, url := range c.Databases {
+		var envVar string
+		for _, check := range dbChecks {
+			if check.Name == strings.ToLower(name) {
+				envVar = check.EnvVar
+			}
+		}
+		if envVar == "" {
+			return nil, fmt.Errorf("unknown database %q (want postgres, mysql, redis, mongodb, kafka or opensearch)", name)
+		}
+		set(envVar, url)
+	}
+	return vars, nil
+}
+
+// loadConfigFile reads CONFIG_FILE, if set, and exports its values as env
+// vars. Variables already present in the environment take precedence over
+// the file. It runs before anything else reads the environment.
+func loadConfigFile() error {
+	path := os.Getenv("CONFIG_FILE")
+	if path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var cfg fileConfig
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("parsing %s: %w", path, err)
+	}
+	vars, err := cfg.environment()
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	for key, value := range vars {
+		if _, ok := os.LookupEnv(key); !ok {
+			os.Setenv(key, value)
+		}
+	}
+	return nil
+}
+
+// envKind describes the values a numeric environment variable accepts.
+type envKind int
+
+const (
+	envPort        envKind = iota // 1-65535
+	envDuration                   // Go duration or whole seconds, not negative
+	envCount                      // integer >= 0
+	envPositiveInt                // integer >= 1
+	envRate                       // number >= 0
+	envPercent                    // number from 0 to 100
+)
+
+// numericEnvVars lists every numeric setting so a bad value (often an
+// unsubstituted bind variable) stops startup with a clear message instead
+// of surfacing later as an obscure error or a silently applied default.
+var numericEnvVars = []struct {
+	Name string
+	Kind envKind
+}{
+	{"PORT", envPort},
+	{"PPROF_PORT", envPort},
+	{"SHUTDOWN_TIMEOUT", envDuration},
+	{"SERVER_READ_HEADER_TIMEOUT", envDuration},
+	{"SERVER_READ_TIMEOUT", envDuration},
+	{"SERVER_WRITE_TIMEOUT", envDuration},
+	{"SERVER_IDLE_TIMEOUT", envDuration},
+	{"CHECK_TIMEOUT", envDuration},
+	{"CHECK_RETRIES", envPositiveInt},
+	{"CHECK_RETRY_BACKOFF", envDuration},
+	{"POLL_INTERVAL", envDuration},
+	{"WAIT_TIMEOUT", envDuration},
+	{"WATCHDOG_INTERVAL", envDuration},
+	{"WATCHDOG_FAILURES", envPositiveInt},
+	{"EXEC_TIMEOUT", envDuration},
+	{"REQUEST_TIMEOUT", envDuration},
+	{"CLOCK_SKEW_THRESHOLD", envDuration},
+	{"RATE_LIMIT", envRate},
+	{"RATE_LIMIT_BURST", envPositiveInt},
+	{"LOG_BUFFER_SIZE", envCount},
+	{"GOROUTINE_WARN", envPositiveInt},
+	{"DISK_MIN_FREE_PCT", envPercent},
+	{"LOG_STREAM_MAX_SUBSCRIBERS", envPositiveInt},
+}
+
+// validateNumericEnv checks every variable in numericEnvVars that is set and
+// returns one error per invalid value.
+func validateNumericEnv() []error {
+	var errs []error
+	for _, v := range numericEnvVars {
+		raw, ok := os.LookupEnv(v.Name)
+		if !ok || raw == "" {
+			continue
+		}
+		var want string
+		switch v.Kind {
+		case envPort:
+			if n, err := strconv.Atoi(raw); err != nil || n < 1 || n > 65535 {
+				want = "a port number between 1 and 65535"
+			}
+		case envDuration:
+			if _, err := strconv.Atoi(raw); err != nil {
+				if d, err := time.ParseDuration(raw); err != nil || d < 0 {
+					want = "a duration such as 10s or a whole number of seconds"
+				}
+			} else if strings.HasPrefix(raw, "-") {
+				want = "a duration such as 10s or a whole number of seconds"
+			}
+		case envCount:
+			if n, err := strconv.Atoi(raw); err != nil || n < 0 {
+				want = "a whole number of 0 or more"
+			}
+		case envPositiveInt:
+			if n, err := strconv.Atoi(raw); err != nil || n < 1 {
+				want = "a whole number of 1 or more"
+			}
+		case envRate:
+			if f, err := strconv.ParseFloat(raw, 64); err != nil || f < 0 {
+				want = "a number of 0 or more"
+			}
+		case envPercent:
+			if f, err := strconv.ParseFloat(raw, 64); err != nil || f < 0 || f > 100 {
+				want = "a percentage from 0 to 100"
+			}
+		}
+		if want != "" {
+			errs = append(errs, fmt.Errorf("%s must be %s, got %q", v.Name, want, raw))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// errMalformedConnString is returned for connection strings that can't be a
+// URL at all, most often an App Platform bind variable such as
+// ${db.DATABASE_URL} that was never substituted.
+var errMalformedConnString = errors.New("malformed connection string — possible unsubstituted bind variable")
+
+// connStringSchemes lists the schemes accepted for each expected scheme.
+var connStringSchemes = map[string][]string{
+	"postgres": {"postgres", "postgresql"},
+	"mysql":    {"mysql"},
+	"redis":    {"redis", "rediss"},
+	"mongodb":  {"mongodb", "mongodb+srv"},
+	"http":     {"http", "https"},
+}
+
+// validateConnString checks that raw parses as a URL with a host and one of
+// the schemes accepted for expectedScheme, so the checks can report a clear
+// error instead of a confusing dial failure.
+func validateConnString(raw, expectedScheme string) error {
+	raw = strings.TrimSpace(raw)
+	if raw == "" || strings.Contains(raw, "${") || !strings.Contains(raw, "://") {
+		return errMalformedConnString
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return errMalformedConnString
+	}
+
+	accepted, ok := connStringSchemes[expectedScheme]
+	if !ok {
+		accepted = []string{expectedScheme}
+	}
+	scheme := strings.ToLower(u.Scheme)
+	for _, s := range accepted {
+		if scheme == s {
+			return nil
+		}
+	}
+	return fmt.Errorf("unexpected scheme %q in connection string, want %s", u.Scheme, strings.Join(accepted, " or "))
+}
+
+var (
+	// secretQueryParamRe matches credential-bearing query parameters.
+	secretQueryParamRe = regexp.MustCompile(`(?i)((?:^|&)(?:password|pass|pwd|sslpassword|secret|token)=)[^&]*`)
+	// dsnPasswordRe matches password=... in a key=value DSN, quoted or not.
+	dsnPasswordRe = regexp.MustCompile(`(?i)\b(password\s*=\s*)('(?:[^'\\]|\\.)*'|\S+)`)
+	// embeddedURLRe finds URLs inside free text such as error messages.
+	embeddedURLRe = regexp.MustCompile(`[a-zA-Z][a-zA-Z0-9+.-]*://[^\s"'<>]+`)
+)
+
+// redactConnString replaces the password in a connection string with ***.
+// It handles URLs (postgres://, redis://, mongodb+srv://, mysql://, ...),
+// lib/pq key=value DSNs and go-sql-driver user:pass@tcp(host)/db DSNs, and
+// copes with passwords containing unescaped characters such as @ / # ?.
+func redactConnString(raw string) string {
+	scheme, rest, isURL := strings.Cut(raw, "://")
+	if !isURL {
+		if strings.Contains(raw, "=") && !strings.Contains(raw, "@") {
+			return dsnPasswordRe.ReplaceAllString(raw, "${1}***")
+		}
+		return redactUserinfo(raw)
+	}
+
+	u, err := url.Parse(raw)
+	if err == nil && !strings.Contains(u.Path, "@") && !strings.Contains(u.Fragment, "@") {
+		u.RawQuery = secretQueryParamRe.ReplaceAllString(u.RawQuery, "${1}***")
+		if u.User == nil {
+			return u.String()
+		}
+		if _, hasPassword := u.User.Password(); !hasPassword {
+			return u.String()
+		}
+		// url.UserPassword would percent-encode the asterisks, so splice
+		// the userinfo back in by hand.
+		user := url.User(u.User.Username()).String()
+		u.User = nil
+		return strings.Replace(u.String(), "://", "://"+user+":***@", 1)
+	}
+
+	// The URL doesn't parse, usually because the password has characters
+	// that should have been escaped. The userinfo then runs up to the last @.
+	rest = redactUserinfo(rest)
+	if base, query, ok := strings.Cut(rest, "?"); ok {
+		rest = base + "?" + secretQueryParamRe.ReplaceAllString(query, "${1}***")
+	}
+	return scheme + "://" + rest
+}
+
+// redactUserinfo masks the password in a "user:password@host..." string.
+func redactUserinfo(s string) string {
+	at := strings.LastIndex(s, "@")
+	if at < 0 {
+		return s
+	}
+	user, _, hasPassword := strings.Cut(s[:at], ":")
+	if !hasPassword {
+		return s
+	}
+	return user + ":***" + s[at:]
+}
+
+// redactConnStrings redacts every URL embedded in text, for error messages
+// from drivers and parsers that quote the connection string they were given.
+func redactConnStrings(text string) string {
+	return embeddedURLRe.ReplaceAllStringFunc(text, redactConnString)
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"strings"
+)
+
+// allowCORS lets browser dashboards call the diagnostic endpoints. It sets
+// Access-Control-Allow-Origin from CORS_ALLOW_ORIGIN (a single origin, a
+// comma-separated list, or "*") and answers OPTIONS preflights itself so
+// they don't need a bearer token. It is a no-op when CORS_ALLOW_ORIGIN is
+// unset.
+func allowCORS(next http.Handler) http.Handler {
+	raw := strings.TrimSpace(os.Getenv("CORS_ALLOW_ORIGIN"))
+	if raw == "" {
+		return next
+	}
+	allowed := make(map[string]bool)
+	for _, origin := range strings.Split(raw, ",") {
+		allowed[strings.TrimSpace(origin)] = true
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" || (!allowed["*"] && !allowed[origin]) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if allowed["*"] {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+		} else {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Add("Vary", "Origin")
+		}
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			w.Header().Set("Access-Control-Allow-Methods", "GET, HEAD, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+			w.Header().Set("Access-Control-Max-Age", "600")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
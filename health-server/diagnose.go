@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// diagnoseEnvVars are the dependency variables /diagnose.json reports the
+// presence of, alongside anything listed in EXPECTED_ENV.
+var diagnoseEnvVars = []string{
+	"DATABASE_URL",
+	"DATABASE_URL_REPLICA",
+	"DATABASE_URLS",
+	"REDIS_URL",
+	"MYSQL_URL",
+	"MONGODB_URI",
+	"KAFKA_BROKERS",
+	"OPENSEARCH_URL",
+}
+
+type DiagnoseRuntime struct {
+	Type          string `json:"type"`
+	Version       string `json:"version"`
+	GoVersion     string `json:"go_version"`
+	ContainerType string `json:"container_type"`
+	ContainerName string `json:"container_name"`
+	Hostname      string `json:"hostname"`
+	Kernel        string `json:"kernel,omitempty"`
+	NumCPU        int    `json:"num_cpu"`
+}
+
+type DiagnoseMemory struct {
+	GoMemory MemoryStats  `json:"go_memory"`
+	Cgroup   CgroupLimits `json:"cgroup"`
+}
+
+type DiagnoseResponse struct {
+	Timestamp    string                  `json:"timestamp"`
+	Runtime      DiagnoseRuntime         `json:"runtime"`
+	Dependencies map[string]bool         `json:"dependencies"`
+	Connectivity map[string]checkOutcome `json:"connectivity"`
+	Resolvers    []string                `json:"resolvers"`
+	Memory       DiagnoseMemory          `json:"memory"`
+	Disks        []DiskUsage             `json:"disks"`
+	Env          map[string]string       `json:"env"`
+}
+
+// envPresence reports whether each variable is set, empty or missing,
+// without its value.
+func envPresence(keys []string) map[string]string {
+	presence := make(map[string]string, len(keys))
+	for _, key := range keys {
+		value, ok := os.LookupEnv(key)
+		switch {
+		case !ok:
+			presence[key] = "missing"
+		case value == "":
+			presence[key] = "empty"
+		default:
+			presence[key] = "set"
+		}
+	}
+	return presence
+}
+
+// diagnoseHandler serves /diagnose.json: the sections of diagnose.sh as one
+// JSON object, built from the same checks the other endpoints run. It
+// answers 503 when a configured dependency is unreachable.
+func diagnoseHandler(w http.ResponseWriter, r *http.Request) {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	hostname, _ := os.Hostname()
+	kernel, _ := os.ReadFile("/proc/sys/kernel/osrelease")
+
+	response := DiagnoseResponse{
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		Runtime: DiagnoseRuntime{
+			Type:          getRuntimeType(),
+			Version:       getRuntimeVersion(),
+			GoVersion:     runtime.Version(),
+			ContainerType: getContainerType(),
+			ContainerName: getContainerName(),
+			Hostname:      hostname,
+			Kernel:        strings.TrimSpace(string(kernel)),
+			NumCPU:        runtime.NumCPU(),
+		},
+		Dependencies: make(map[string]bool, len(dbChecks)),
+		Resolvers:    systemResolvers(),
+		Memory: DiagnoseMemory{
+			GoMemory: MemoryStats{
+				HeapAllocBytes: mem.HeapAlloc,
+				HeapSysBytes:   mem.HeapSys,
+				SysBytes:       mem.Sys,
+				NumGC:          mem.NumGC,
+			},
+			Cgroup: readCgroupLimits(),
+		},
+		Disks: []DiskUsage{diskUsage("/"), diskUsage("/tmp")},
+	}
+	for _, check := range dbChecks {
+		response.Dependencies[check.Name] = check.Configured()
+	}
+
+	envKeys := append([]string(nil), diagnoseEnvVars...)
+	if expected, err := getExpectedEnv(); err == nil {
+		envKeys = append(envKeys, expected...)
+	}
+	response.Env = envPresence(envKeys)
+
+	ctx, cancel := context.WithTimeout(r.Context(), checkTimeout(r.Context()))
+	defer cancel()
+	results, ok := runAllChecks(ctx)
+	response.Connectivity = results
+	writeCheckResult(w, ok, response)
+}
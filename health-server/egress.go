@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// egressLookupTimeout bounds a single request to the IP-echo service.
+const egressLookupTimeout = 3 * time.Second
+
+// egressCacheTTL is how long a discovered egress IP is reused.
+const egressCacheTTL = 5 * time.Minute
+
+type EgressResponse struct {
+	IP        string `json:"ip,omitempty"`
+	Source    string `json:"source"`
+	CheckedAt string `json:"checked_at"`
+	Cached    bool   `json:"cached"`
+	Error     string `json:"error,omitempty"`
+}
+
+// egressCache remembers the last successful egress lookup. The lock isn't
+// held during a lookup, so reading the cache never waits on the network.
+type egressCache struct {
+	mu         sync.Mutex
+	ip         string
+	checkedAt  time.Time
+	refreshing atomic.Bool
+}
+
+var egress egressCache
+
+// get returns the cached egress IP, looking it up again once the cache entry
+// is older than egressCacheTTL. Failed lookups are not cached.
+func (c *egressCache) get(ctx context.Context) (ip string, checkedAt time.Time, cached bool, err error) {
+	if ip, checkedAt, fresh := c.cached(); fresh {
+		return ip, checkedAt, true, nil
+	}
+	ip, err = lookupEgressIP(ctx)
+	if err != nil {
+		return "", time.Now(), false, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ip, c.checkedAt = ip, time.Now()
+	return c.ip, c.checkedAt, false, nil
+}
+
+// cached returns the last egress IP found, however old, without a lookup,
+// and whether it is still within egressCacheTTL.
+func (c *egressCache) cached() (ip string, checkedAt time.Time, fresh bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ip, c.checkedAt, c.ip != "" && time.Since(c.checkedAt) < egressCacheTTL
+}
+
+// refresh looks the egress IP up in the background, unless a lookup started
+// here is still running.
+func (c *egressCache) refresh() {
+	if !c.refreshing.CompareAndSwap(false, true) {
+		return
+	}
+	go func() {
+		defer c.refreshing.Store(false)
+		c.get(context.Background())
+	}()
+}
+
+// logEgressIP looks up the egress IP and logs it: the address to add to a
+// managed database's trusted sources. main runs it in the background once
+// the server is up, so a container without outbound access starts as fast
+// as any other.
+func logEgressIP() {
+	ip, _, _, err := egress.get(context.Background())
+	if err != nil {
+		slog.Warn("egress IP lookup failed", "url", getEgressCheckURL(), "error", err)
+		return
+	}
+	slog.Info("egress IP", "ip", ip)
+}
+
+// getEgressCheckURL returns the IP-echo service used to discover the
+// container's public source IP (EGRESS_CHECK_URL).
+func getEgressCheckURL() string {
+	if val := os.Getenv("EGRESS_CHECK_URL"); val != "" {
+		return val
+	}
+	return "https://api.ipify.org"
+}
+
+// lookupEgressIP asks the IP-echo service which address our requests come
+// from. On App Platform this is the IP to add to database trusted sources.
+func lookupEgressIP(ctx context.Context) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, egressLookupTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, getEgressCheckURL(), nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s returned %s", getEgressCheckURL(), resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 256))
+	if err != nil {
+		return "", err
+	}
+	ip := strings.TrimSpace(string(body))
+	if net.ParseIP(ip) == nil {
+		return "", fmt.Errorf("%s returned %q, not an IP address", getEgressCheckURL(), ip)
+	}
+	return ip, nil
+}
+
+// egressHandler reports the public IP outbound requests appear to come from.
+func egressHandler(w http.ResponseWriter, r *http.Request) {
+	ip, checkedAt, cached, err := egress.get(r.Context())
+	response := EgressResponse{
+		IP:        ip,
+		Source:    getEgressCheckURL(),
+		CheckedAt: checkedAt.UTC().Format(time.RFC3339),
+		Cached:    cached,
+	}
+	if err != nil {
+		response.Error = err.Error()
+	}
+	writeCheckResult(w, err == nil, response)
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+)
+
+const redactedValue = "***redacted***"
+
+// secretKeyPatterns mark environment variables whose values are hidden
+// unless REVEAL_SECRETS=true.
+var secretKeyPatterns = []string{"URL", "URI", "PASSWORD", "SECRET", "TOKEN", "KEY"}
+
+func isSecretKey(key string) bool {
+	upper := strings.ToUpper(key)
+	for _, pattern := range secretKeyPatterns {
+		if strings.Contains(upper, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactEnvValue hides the value of secret-looking keys.
+func redactEnvValue(key, value string) string {
+	if value != "" && isSecretKey(key) && os.Getenv("REVEAL_SECRETS") != "true" {
+		return redactedValue
+	}
+	return value
+}
+
+// envHandler returns the process environment, optionally filtered by
+// ?prefix=, with secret values redacted.
+func envHandler(w http.ResponseWriter, r *http.Request) {
+	prefix := r.URL.Query().Get("prefix")
+	vars := make(map[string]string)
+	for _, entry := range os.Environ() {
+		key, value, _ := strings.Cut(entry, "=")
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		vars[key] = redactEnvValue(key, value)
+	}
+	writeJSON(w, http.StatusOK, vars)
+}
+
+type EnvVarStatus struct {
+	Status string `json:"status"`
+	Value  string `json:"value,omitempty"`
+}
+
+type EnvDiffResponse struct {
+	Variables     map[string]EnvVarStatus `json:"variables"`
+	Missing       []string                `json:"missing"`
+	Empty         []string                `json:"empty"`
+	Unsubstituted []string                `json:"unsubstituted"`
+	Error         string                  `json:"error,omitempty"`
+}
+
+// getExpectedEnv returns the variable names listed in EXPECTED_ENV
+// (comma-separated) and in the file named by EXPECTED_ENV_FILE (one per
+// line, # comments allowed).
+func getExpectedEnv() ([]string, error) {
+	seen := make(map[string]bool)
+	var keys []string
+	add := func(key string) {
+		key = strings.TrimSpace(key)
+		if key != "" && !strings.HasPrefix(key, "#") && !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	for _, key := range strings.Split(os.Getenv("EXPECTED_ENV"), ",") {
+		add(key)
+	}
+	if path := os.Getenv("EXPECTED_ENV_FILE"); path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			add(scanner.Text())
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return keys, nil
+}
+
+// envDiffHandler compares the expected variables with the environment. A
+// value still containing "${" is an App Platform bind variable that was
+// never substituted; it is shown verbatim since it holds no secret.
+func envDiffHandler(w http.ResponseWriter, r *http.Request) {
+	response := EnvDiffResponse{
+		Variables:     make(map[string]EnvVarStatus),
+		Missing:       []string{},
+		Empty:         []string{},
+		Unsubstituted: []string{},
+	}
+	keys, err := getExpectedEnv()
+	if err != nil {
+		response.Error = fmt.Sprintf("reading EXPECTED_ENV_FILE: %v", err)
+		writeCheckResult(w, false, response)
+		return
+	}
+	if len(keys) == 0 {
+		response.Error = "set EXPECTED_ENV or EXPECTED_ENV_FILE to the variables this app needs"
+		writeCheckResult(w, false, response)
+		return
+	}
+
+	for _, key := range keys {
+		value, ok := os.LookupEnv(key)
+		switch {
+		case !ok:
+			response.Variables[key] = EnvVarStatus{Status: "missing"}
+			response.Missing = append(response.Missing, key)
+		case value == "":
+			response.Variables[key] = EnvVarStatus{Status: "empty"}
+			response.Empty = append(response.Empty, key)
+		case strings.Contains(value, "${"):
+			response.Variables[key] = EnvVarStatus{Status: "unsubstituted", Value: redactConnString(value)}
+			response.Unsubstituted = append(response.Unsubstituted, key)
+		default:
+			response.Variables[key] = EnvVarStatus{Status: "present", Value: redactEnvValue(key, value)}
+		}
+	}
+	sort.Strings(response.Missing)
+	sort.Strings(response.Empty)
+	sort.Strings(response.Unsubstituted)
+	ok := len(response.Missing)+len(response.Empty)+len(response.Unsubstituted) == 0
+	writeCheckResult(w, ok, response)
+}
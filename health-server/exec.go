@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// execAllowList names the scripts /exec may run. Anything else is rejected
+// so the endpoint can't be used for arbitrary command execution.
+var execAllowList = map[string]bool{
+	"diagnose":          true,
+	"test-db":           true,
+	"test-connectivity": true,
+}
+
+// maxExecArgs limits how many ?arg= values are passed to a script.
+const maxExecArgs = 4
+
+// execHandler serves /exec?script=diagnose[&arg=postgres]. Output is streamed
+// line by line using chunked encoding, and the exit code is sent in the
+// X-Exit-Code trailer.
+func execHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	script := query.Get("script")
+	args := query["arg"]
+	if !execAllowList[script] {
+		writeError(w, http.StatusBadRequest, "script must be one of: diagnose, test-db, test-connectivity")
+		return
+	}
+	if len(args) > maxExecArgs {
+		writeError(w, http.StatusBadRequest, "too many arguments")
+		return
+	}
+
+	timeout := getEnvDuration("EXEC_TIMEOUT", 120*time.Second)
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+	// Scripts may outlive the server's default write timeout.
+	http.NewResponseController(w).SetWriteDeadline(time.Now().Add(timeout + 5*time.Second))
+
+	// Arguments are passed directly to the script, never through a shell.
+	cmd := exec.CommandContext(ctx, filepath.Join(getScriptsDir(), script+".sh"), args...)
+	pr, pw := io.Pipe()
+	cmd.Stdout = pw
+	cmd.Stderr = pw
+	// Don't wait forever on grandchildren that keep the output pipe open.
+	cmd.WaitDelay = 5 * time.Second
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Trailer", "X-Exit-Code")
+	w.WriteHeader(http.StatusOK)
+	rc := http.NewResponseController(w)
+	rc.Flush()
+
+	runErr := make(chan error, 1)
+	if err := cmd.Start(); err != nil {
+		pw.Close()
+		runErr <- err
+	} else {
+		go func() {
+			err := cmd.Wait()
+			pw.Close()
+			runErr <- err
+		}()
+	}
+
+	// Relay output a line at a time so clients like `curl -N` see progress.
+	reader := bufio.NewReader(pr)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			w.Write(line)
+			rc.Flush()
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	exitCode := 0
+	if err := <-runErr; err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			exitCode = exitErr.ExitCode()
+		} else {
+			exitCode = -1
+			w.Write([]byte("\nexec failed: " + err.Error() + "\n"))
+		}
+		if ctx.Err() == context.DeadlineExceeded {
+			w.Write([]byte("\nscript timed out after " + timeout.String() + "\n"))
+		}
+	}
+	w.Header().Set("X-Exit-Code", strconv.Itoa(exitCode))
+}
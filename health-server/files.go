@@ -0,0 +1,235 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// maxViewableFileSize caps what /file will return.
+const maxViewableFileSize = 1 << 20
+
+// viewableDirs returns VIEWABLE_DIRS (comma-separated, default /app and
+// /tmp) with symlinks resolved, skipping directories that don't exist.
+func viewableDirs() []string {
+	raw := os.Getenv("VIEWABLE_DIRS")
+	if raw == "" {
+		raw = "/app,/tmp"
+	}
+	var dirs []string
+	for _, dir := range strings.Split(raw, ",") {
+		dir = strings.TrimSpace(dir)
+		if dir == "" {
+			continue
+		}
+		resolved, err := filepath.EvalSymlinks(filepath.Clean(dir))
+		if err != nil {
+			continue
+		}
+		dirs = append(dirs, resolved)
+	}
+	return dirs
+}
+
+// withinDir reports whether path is dir or lies beneath it.
+func withinDir(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// errPathNotAllowed is returned for paths outside VIEWABLE_DIRS.
+var errPathNotAllowed = errors.New("path is outside VIEWABLE_DIRS")
+
+// resolveViewablePath checks a requested path and returns it with symlinks
+// resolved, so a link inside an allowed directory can't point outside it.
+func resolveViewablePath(path string) (string, error) {
+	if path == "" {
+		return "", errors.New("path is required")
+	}
+	if !filepath.IsAbs(path) {
+		return "", errors.New("path must be absolute")
+	}
+	for _, elem := range strings.Split(path, "/") {
+		if elem == ".." {
+			return "", errors.New("path must not contain ..")
+		}
+	}
+	resolved, err := filepath.EvalSymlinks(filepath.Clean(path))
+	if err != nil {
+		return "", err
+	}
+	for _, dir := range viewableDirs() {
+		if withinDir(resolved, dir) {
+			return resolved, nil
+		}
+	}
+	return "", errPathNotAllowed
+}
+
+// viewPathError maps a resolveViewablePath or stat failure to a response.
+func viewPathError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errPathNotAllowed):
+		writeError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, fs.ErrNotExist):
+		writeError(w, http.StatusNotFound, "no such file or directory")
+	case errors.Is(err, fs.ErrPermission):
+		writeError(w, http.StatusForbidden, "permission denied")
+	default:
+		writeError(w, http.StatusBadRequest, err.Error())
+	}
+}
+
+// fileAccessEnabled reports whether /file and /ls may run, which requires
+// AUTH_TOKEN, and answers 403 when they may not.
+func fileAccessEnabled(w http.ResponseWriter) bool {
+	if os.Getenv("AUTH_TOKEN") == "" {
+		writeError(w, http.StatusForbidden, "file access is disabled; set AUTH_TOKEN to enable /file and /ls")
+		return false
+	}
+	return true
+}
+
+// fileHandler serves /file?path=/app/config.yaml: the contents of a file
+// under VIEWABLE_DIRS. Content is always served as text/plain or
+// application/octet-stream so a viewed file can't run as a page in the
+// browser.
+func fileHandler(w http.ResponseWriter, r *http.Request) {
+	if !fileAccessEnabled(w) {
+		return
+	}
+	path, err := resolveViewablePath(r.URL.Query().Get("path"))
+	if err != nil {
+		viewPathError(w, err)
+		return
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		viewPathError(w, err)
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		viewPathError(w, err)
+		return
+	}
+	if info.IsDir() {
+		writeError(w, http.StatusBadRequest, "path is a directory")
+		return
+	}
+	if !info.Mode().IsRegular() {
+		writeError(w, http.StatusBadRequest, "path is not a regular file")
+		return
+	}
+	if info.Size() > maxViewableFileSize {
+		writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("file is %d bytes; the limit is %d", info.Size(), maxViewableFileSize))
+		return
+	}
+
+	data, err := io.ReadAll(io.LimitReader(f, maxViewableFileSize))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	contentType := "application/octet-stream"
+	if utf8.Valid(data) && !bytes.ContainsRune(data, 0) {
+		contentType = "text/plain; charset=utf-8"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	http.ServeContent(w, r, filepath.Base(path), info.ModTime(), bytes.NewReader(data))
+}
+
+// maxDirEntries caps how many entries /ls returns.
+const maxDirEntries = 1000
+
+type DirEntry struct {
+	Name          string `json:"name"`
+	Type          string `json:"type"`
+	Size          int64  `json:"size"`
+	Mode          string `json:"mode"`
+	ModTime       string `json:"mtime"`
+	SymlinkTarget string `json:"symlink_target,omitempty"`
+}
+
+type DirListing struct {
+	Dir       string     `json:"dir"`
+	Entries   []DirEntry `json:"entries"`
+	Truncated bool       `json:"truncated,omitempty"`
+}
+
+// entryType names the kind of file described by mode.
+func entryType(mode fs.FileMode) string {
+	switch {
+	case mode.IsDir():
+		return "dir"
+	case mode.IsRegular():
+		return "file"
+	case mode&fs.ModeSymlink != 0:
+		return "symlink"
+	default:
+		return "other"
+	}
+}
+
+// lsHandler serves /ls?dir=/app/scripts: the entries of a directory under
+// VIEWABLE_DIRS, sorted by name. Symlinks are listed, not followed.
+func lsHandler(w http.ResponseWriter, r *http.Request) {
+	if !fileAccessEnabled(w) {
+		return
+	}
+	dir, err := resolveViewablePath(r.URL.Query().Get("dir"))
+	if err != nil {
+		viewPathError(w, err)
+		return
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		viewPathError(w, err)
+		return
+	}
+	if !info.IsDir() {
+		writeError(w, http.StatusBadRequest, "dir is not a directory")
+		return
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		viewPathError(w, err)
+		return
+	}
+
+	listing := DirListing{Dir: dir, Entries: []DirEntry{}}
+	for _, entry := range entries {
+		if len(listing.Entries) == maxDirEntries {
+			listing.Truncated = true
+			break
+		}
+		info, err := entry.Info()
+		if err != nil {
+			// Removed since ReadDir.
+			continue
+		}
+		item := DirEntry{
+			Name:    entry.Name(),
+			Type:    entryType(info.Mode()),
+			Size:    info.Size(),
+			Mode:    info.Mode().String(),
+			ModTime: info.ModTime().UTC().Format(time.RFC3339),
+		}
+		if item.Type == "symlink" {
+			item.SymlinkTarget, _ = os.Readlink(filepath.Join(dir, entry.Name()))
+		}
+		listing.Entries = append(listing.Entries, item)
+	}
+	writeJSON(w, http.StatusOK, listing)
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// errInvalid marks test cases rejected before any filesystem lookup.
+var errInvalid = errors.New("invalid path")
+
+func TestResolveViewablePath(t *testing.T) {
+	base, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	allowed := filepath.Join(base, "app")
+	sibling := filepath.Join(base, "app2")
+	outside := filepath.Join(base, "outside")
+	for _, dir := range []string{filepath.Join(allowed, "sub"), sibling, outside} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, file := range []string{filepath.Join(allowed, "config.yaml"), filepath.Join(sibling, "secret"), filepath.Join(outside, "secret")} {
+		if err := os.WriteFile(file, []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(filepath.Join(outside, "secret"), filepath.Join(allowed, "escape")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(allowed, "escape-dir")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(allowed, "config.yaml"), filepath.Join(allowed, "sub", "link")); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("VIEWABLE_DIRS", allowed)
+
+	tests := []struct {
+		name    string
+		path    string
+		want    string // resolved path when allowed
+		wantErr error  // errPathNotAllowed, fs.ErrNotExist, or errInvalid for other rejections
+	}{
+		{name: "file in allowed dir", path: filepath.Join(allowed, "config.yaml"), want: filepath.Join(allowed, "config.yaml")},
+		{name: "allowed dir itself", path: allowed, want: allowed},
+		{name: "symlink staying inside", path: filepath.Join(allowed, "sub", "link"), want: filepath.Join(allowed, "config.yaml")},
+		{name: "dot dot escaping", path: allowed + "/../outside/secret", wantErr: errInvalid},
+		{name: "dot dot staying inside", path: allowed + "/sub/../config.yaml", wantErr: errInvalid},
+		{name: "symlink to file outside", path: filepath.Join(allowed, "escape"), wantErr: errPathNotAllowed},
+		{name: "file through symlinked dir outside", path: filepath.Join(allowed, "escape-dir", "secret"), wantErr: errPathNotAllowed},
+		{name: "sibling prefix dir", path: filepath.Join(sibling, "secret"), wantErr: errPathNotAllowed},
+		{name: "outside dir", path: filepath.Join(outside, "secret"), wantErr: errPathNotAllowed},
+		{name: "relative path", path: "app/config.yaml", wantErr: errInvalid},
+		{name: "empty path", path: "", wantErr: errInvalid},
+		{name: "missing file", path: filepath.Join(allowed, "missing.yaml"), wantErr: fs.ErrNotExist},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveViewablePath(tt.path)
+			switch {
+			case tt.wantErr == nil:
+				if err != nil || got != tt.want {
+					t.Errorf("resolveViewablePath(%q) = %q, %v; want %q", tt.path, got, err, tt.want)
+				}
+			case tt.wantErr == errInvalid:
+				if err == nil || errors.Is(err, errPathNotAllowed) || errors.Is(err, fs.ErrNotExist) {
+					t.Errorf("resolveViewablePath(%q) = %q, %v; want a validation error", tt.path, got, err)
+				}
+			default:
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("resolveViewablePath(%q) = %q, %v; want %v", tt.path, got, err, tt.wantErr)
+				}
+			}
+		})
+	}
+}
+
+func TestWithinDir(t *testing.T) {
+	tests := []struct {
+		path, dir string
+		want      bool
+	}{
+		{"/app", "/app", true},
+		{"/app/config.yaml", "/app", true},
+		{"/app/..hidden", "/app", true},
+		{"/app2", "/app", false},
+		{"/app2/secret", "/app", false},
+		{"/", "/app", false},
+		{"/etc/passwd", "/app", false},
+	}
+	for _, tt := range tests {
+		if got := withinDir(tt.path, tt.dir); got != tt.want {
+			t.Errorf("withinDir(%q, %q) = %v, want %v", tt.path, tt.dir, got, tt.want)
+		}
+	}
+}
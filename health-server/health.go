@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// healthState tracks why the container should report itself unhealthy.
+// Each source (a critical dependency, or "manual") contributes at most one
+// reason; the container is healthy when there are none.
+type healthState struct {
+	mu      sync.RWMutex
+	reasons map[string]string
+}
+
+var health = &healthState{reasons: make(map[string]string)}
+
+func (h *healthState) setUnhealthy(source, reason string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.reasons[source] = reason
+}
+
+func (h *healthState) clear(source string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.reasons, source)
+}
+
+// status reports whether the container is healthy, and if not, why.
+func (h *healthState) status() (bool, []string) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	reasons := make([]string, 0, len(h.reasons))
+	for source, reason := range h.reasons {
+		reasons = append(reasons, fmt.Sprintf("%s: %s", source, reason))
+	}
+	sort.Strings(reasons)
+	return len(reasons) == 0, reasons
+}
+
+// DiskCheck is the free-space check /health runs when DISK_MIN_FREE_PCT is
+// set.
+type DiskCheck struct {
+	Path       string  `json:"path"`
+	TotalBytes uint64  `json:"total_bytes"`
+	FreeBytes  uint64  `json:"free_bytes"`
+	FreePct    float64 `json:"free_pct"`
+	MinFreePct float64 `json:"min_free_pct"`
+	Low        bool    `json:"low"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// checkDiskSpace compares the free space on DISK_CHECK_PATH (default /tmp,
+// where diagnostic output tends to pile up) against DISK_MIN_FREE_PCT. It
+// returns nil when DISK_MIN_FREE_PCT is unset. A path that can't be read is
+// reported but doesn't fail the health check.
+func checkDiskSpace() *DiskCheck {
+	minFree, err := strconv.ParseFloat(os.Getenv("DISK_MIN_FREE_PCT"), 64)
+	if err != nil {
+		return nil
+	}
+	path := os.Getenv("DISK_CHECK_PATH")
+	if path == "" {
+		path = "/tmp"
+	}
+
+	usage := diskUsage(path)
+	check := &DiskCheck{
+		Path:       path,
+		TotalBytes: usage.TotalBytes,
+		FreeBytes:  usage.FreeBytes,
+		MinFreePct: minFree,
+		Error:      usage.Error,
+	}
+	if usage.Error == "" && usage.TotalBytes > 0 {
+		freePct := float64(usage.FreeBytes) / float64(usage.TotalBytes) * 100
+		check.FreePct = math.Round(freePct*10) / 10
+		check.Low = freePct < minFree
+	}
+	return check
+}
+
+// isCriticalDependency reports whether a failing check for name should make
+// /health fail. Critical dependencies are listed in CRITICAL_DEPENDENCIES
+// (e.g. "postgres,redis"); by default none are.
+func isCriticalDependency(name string) bool {
+	for _, dep := range strings.Split(os.Getenv("CRITICAL_DEPENDENCIES"), ",") {
+		if strings.EqualFold(strings.TrimSpace(dep), name) {
+			return true
+		}
+	}
+	return false
+}
+
+// updateDependencyHealth feeds a check result into the health state.
+func updateDependencyHealth(name string, ok bool) {
+	if !isCriticalDependency(name) {
+		return
+	}
+	if ok {
+		health.clear(name)
+	} else {
+		health.setUnhealthy(name, "critical dependency unreachable")
+	}
+}
+
+// unhealthyHandler lets operators force /health to fail: POST marks the
+// container unhealthy (with an optional ?reason=), DELETE restores it. A
+// failing /health gets the container restarted, so like the admin endpoints
+// it refuses to run unless AUTH_TOKEN is set.
+func unhealthyHandler(w http.ResponseWriter, r *http.Request) {
+	if os.Getenv("AUTH_TOKEN") == "" {
+		writeError(w, http.StatusForbidden, "/unhealthy is disabled; set AUTH_TOKEN to enable it")
+		return
+	}
+	switch r.Method {
+	case http.MethodPost:
+		reason := r.URL.Query().Get("reason")
+		if reason == "" {
+			reason = "marked unhealthy by operator"
+		}
+		health.setUnhealthy("manual", reason)
+	case http.MethodDelete:
+		health.clear("manual")
+	default:
+		w.Header().Set("Allow", "POST, DELETE")
+		writeError(w, http.StatusMethodNotAllowed, "use POST to mark unhealthy or DELETE to restore")
+		return
+	}
+
+	healthy, reasons := health.status()
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"healthy": healthy,
+		"reasons": reasons,
+	})
+}
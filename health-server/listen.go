@@ -0,0 +1,59 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"time"
+)
+
+// socketPath returns LISTEN_SOCKET, the Unix socket to serve on instead of
+// TCP, or "" to listen on BIND_ADDR:PORT.
+func socketPath() string {
+	return os.Getenv("LISTEN_SOCKET")
+}
+
+// listen opens the main server's listener: a Unix socket at LISTEN_SOCKET
+// when set, otherwise TCP on addr.
+func listen(addr string) (net.Listener, error) {
+	path := socketPath()
+	if path == "" {
+		return net.Listen("tcp", addr)
+	}
+	if err := removeStaleSocket(path); err != nil {
+		return nil, err
+	}
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	// Containers sharing the volume usually run as a different user.
+	if err := os.Chmod(path, 0o666); err != nil {
+		ln.Close()
+		return nil, err
+	}
+	return ln, nil
+}
+
+// removeStaleSocket deletes a socket file left behind by a previous run. It
+// refuses to remove anything that isn't a socket, or a socket another
+// process is still accepting connections on.
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.Mode()&fs.ModeSocket == 0 {
+		return fmt.Errorf("LISTEN_SOCKET %s exists and is not a socket", path)
+	}
+	if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+		conn.Close()
+		return fmt.Errorf("LISTEN_SOCKET %s is in use by another process", path)
+	}
+	return os.Remove(path)
+}
@@ -0,0 +1,304 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultLogBufferSize is how many log lines /logs keeps when
+// LOG_BUFFER_SIZE is unset.
+const defaultLogBufferSize = 500
+
+// LogEntry is one captured log record.
+type LogEntry struct {
+	Time    string         `json:"time"`
+	Level   string         `json:"level"`
+	Message string         `json:"msg"`
+	Attrs   map[string]any `json:"attrs,omitempty"`
+	level   slog.Level
+}
+
+// String formats the entry like slog's text handler.
+func (e LogEntry) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %-5s %s", e.Time, e.Level, e.Message)
+	keys := make([]string, 0, len(e.Attrs))
+	for key := range e.Attrs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(&b, " %s=%v", key, e.Attrs[key])
+	}
+	return b.String()
+}
+
+// logRing keeps the most recent log entries in a fixed-size ring and fans
+// new entries out to /logs/stream subscribers.
+type logRing struct {
+	mu          sync.Mutex
+	entries     []LogEntry
+	next        int
+	full        bool
+	subscribers map[chan LogEntry]struct{}
+}
+
+func newLogRing(size int) *logRing {
+	return &logRing{
+		entries:     make([]LogEntry, size),
+		subscribers: make(map[chan LogEntry]struct{}),
+	}
+}
+
+func (l *logRing) add(entry LogEntry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries[l.next] = entry
+	l.next = (l.next + 1) % len(l.entries)
+	if l.next == 0 {
+		l.full = true
+	}
+	for ch := range l.subscribers {
+		// A subscriber that can't keep up misses entries rather than
+		// blocking logging.
+		select {
+		case ch <- entry:
+		default:
+		}
+	}
+}
+
+// snapshot returns the buffered entries, oldest first.
+func (l *logRing) snapshot() []LogEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.snapshotLocked()
+}
+
+func (l *logRing) snapshotLocked() []LogEntry {
+	if !l.full {
+		return append([]LogEntry(nil), l.entries[:l.next]...)
+	}
+	return append(append([]LogEntry(nil), l.entries[l.next:]...), l.entries[:l.next]...)
+}
+
+// subscribe registers a channel that receives every entry added from now
+// on, along with the entries already buffered. It fails once max
+// subscribers are connected. The returned func unsubscribes.
+func (l *logRing) subscribe(max int) (<-chan LogEntry, []LogEntry, func(), error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.subscribers) >= max {
+		return nil, nil, nil, fmt.Errorf("too many log stream subscribers (max %d)", max)
+	}
+	ch := make(chan LogEntry, 64)
+	l.subscribers[ch] = struct{}{}
+	unsubscribe := func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		delete(l.subscribers, ch)
+	}
+	return ch, l.snapshotLocked(), unsubscribe, nil
+}
+
+// logBuffer holds recent log lines for /logs; nil when LOG_BUFFER_SIZE=0.
+var logBuffer *logRing
+
+// ringHandler is a slog.Handler that records into a logRing.
+type ringHandler struct {
+	ring   *logRing
+	level  slog.Leveler
+	attrs  map[string]any
+	prefix string
+}
+
+func (h *ringHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *ringHandler) Handle(_ context.Context, r slog.Record) error {
+	entry := LogEntry{
+		Time:    r.Time.UTC().Format(time.RFC3339Nano),
+		Level:   r.Level.String(),
+		Message: r.Message,
+		level:   r.Level,
+	}
+	if len(h.attrs) > 0 || r.NumAttrs() > 0 {
+		entry.Attrs = make(map[string]any, len(h.attrs)+r.NumAttrs())
+		for key, value := range h.attrs {
+			entry.Attrs[key] = value
+		}
+		r.Attrs(func(a slog.Attr) bool {
+			addLogAttr(entry.Attrs, h.prefix, a)
+			return true
+		})
+	}
+	h.ring.add(entry)
+	return nil
+}
+
+func (h *ringHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := *h
+	clone.attrs = make(map[string]any, len(h.attrs)+len(attrs))
+	for key, value := range h.attrs {
+		clone.attrs[key] = value
+	}
+	for _, a := range attrs {
+		addLogAttr(clone.attrs, h.prefix, a)
+	}
+	return &clone
+}
+
+func (h *ringHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	clone := *h
+	clone.prefix = h.prefix + name + "."
+	return &clone
+}
+
+// addLogAttr stores a under prefix+key, flattening groups into dotted keys
+// and turning values that don't marshal well (errors, durations) into
+// strings.
+func addLogAttr(attrs map[string]any, prefix string, a slog.Attr) {
+	value := a.Value.Resolve()
+	switch value.Kind() {
+	case slog.KindGroup:
+		groupPrefix := prefix
+		if a.Key != "" {
+			groupPrefix += a.Key + "."
+		}
+		for _, member := range value.Group() {
+			addLogAttr(attrs, groupPrefix, member)
+		}
+		return
+	case slog.KindDuration, slog.KindTime:
+		attrs[prefix+a.Key] = value.String()
+		return
+	}
+	if err, ok := value.Any().(error); ok {
+		attrs[prefix+a.Key] = err.Error()
+		return
+	}
+	attrs[prefix+a.Key] = value.Any()
+}
+
+// teeHandler sends each record to every handler that accepts its level.
+type teeHandler []slog.Handler
+
+func (t teeHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, h := range t {
+		if h.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t teeHandler) Handle(ctx context.Context, r slog.Record) error {
+	var firstErr error
+	for _, h := range t {
+		if !h.Enabled(ctx, r.Level) {
+			continue
+		}
+		if err := h.Handle(ctx, r.Clone()); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (t teeHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make(teeHandler, len(t))
+	for i, h := range t {
+		handlers[i] = h.WithAttrs(attrs)
+	}
+	return handlers
+}
+
+func (t teeHandler) WithGroup(name string) slog.Handler {
+	handlers := make(teeHandler, len(t))
+	for i, h := range t {
+		handlers[i] = h.WithGroup(name)
+	}
+	return handlers
+}
+
+// getLogBufferSize reads LOG_BUFFER_SIZE (default 500). 0 disables /logs.
+func getLogBufferSize() (int, error) {
+	raw := os.Getenv("LOG_BUFFER_SIZE")
+	if raw == "" {
+		return defaultLogBufferSize, nil
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 0 {
+		return defaultLogBufferSize, fmt.Errorf("invalid LOG_BUFFER_SIZE %q, using %d", raw, defaultLogBufferSize)
+	}
+	return n, nil
+}
+
+type LogsResponse struct {
+	BufferSize int        `json:"buffer_size"`
+	Count      int        `json:"count"`
+	Entries    []LogEntry `json:"entries"`
+}
+
+// logsHandler returns recently buffered log lines, oldest first. ?n= keeps
+// only the last n, ?level= drops entries below that level, and
+// ?format=text returns them as plain lines.
+func logsHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if logBuffer == nil {
+		writeError(w, http.StatusNotFound, "log buffer is disabled (LOG_BUFFER_SIZE=0)")
+		return
+	}
+
+	limit := 0
+	if val := query.Get("n"); val != "" {
+		n, err := strconv.Atoi(val)
+		if err != nil || n < 1 {
+			writeError(w, http.StatusBadRequest, "n must be a positive number")
+			return
+		}
+		limit = n
+	}
+	minLevel := slog.LevelDebug
+	if val := query.Get("level"); val != "" {
+		if err := minLevel.UnmarshalText([]byte(val)); err != nil {
+			writeError(w, http.StatusBadRequest, "level must be one of debug, info, warn, error")
+			return
+		}
+	}
+
+	entries := []LogEntry{}
+	for _, entry := range logBuffer.snapshot() {
+		if entry.level >= minLevel {
+			entries = append(entries, entry)
+		}
+	}
+	if limit > 0 && len(entries) > limit {
+		entries = entries[len(entries)-limit:]
+	}
+
+	if negotiateFormat(r) == "text" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		for _, entry := range entries {
+			fmt.Fprintln(w, entry.String())
+		}
+		return
+	}
+	writeJSON(w, http.StatusOK, LogsResponse{
+		BufferSize: len(logBuffer.entries),
+		Count:      len(entries),
+		Entries:    entries,
+	})
+}
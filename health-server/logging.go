@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bufio"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// setupLogger installs the default slog logger. LOG_FORMAT selects json
+// (default) or text output and LOG_LEVEL sets the minimum level. The last
+// LOG_BUFFER_SIZE records are also kept in memory for /logs.
+func setupLogger() {
+	level := slog.LevelInfo
+	switch strings.ToLower(os.Getenv("LOG_LEVEL")) {
+	case "debug":
+		level = slog.LevelDebug
+	case "warn", "warning":
+		level = slog.LevelWarn
+	case "error":
+		level = slog.LevelError
+	}
+
+	opts := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	if strings.ToLower(os.Getenv("LOG_FORMAT")) == "text" {
+		handler = slog.NewTextHandler(os.Stdout, opts)
+	} else {
+		handler = slog.NewJSONHandler(os.Stdout, opts)
+	}
+
+	bufferSize, bufferErr := getLogBufferSize()
+	if bufferSize > 0 {
+		logBuffer = newLogRing(bufferSize)
+		handler = teeHandler{handler, &ringHandler{ring: logBuffer, level: level}}
+	}
+	slog.SetDefault(slog.New(handler))
+	if bufferErr != nil {
+		slog.Warn(bufferErr.Error())
+	}
+}
+
+// statusRecorder captures the status code written by a handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Hijack lets WebSocket upgrades (/logs/stream) take over the connection.
+func (r *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(r.ResponseWriter).Hijack()
+	if err == nil {
+		r.status = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// logRequests writes an access log entry once the wrapped handler returns,
+// unless ACCESS_LOG=false.
+func logRequests(next http.Handler) http.Handler {
+	accessLog := os.Getenv("ACCESS_LOG") != "false"
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		if !accessLog {
+			return
+		}
+		slog.Info("request",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"remote_addr", r.RemoteAddr,
+			"client_ip", clientIP(r),
+			"user_agent", r.UserAgent(),
+			"status", rec.status,
+			"duration_ms", latencyMs(time.Since(start)),
+		)
+	})
+}
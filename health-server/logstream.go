@@ -0,0 +1,138 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// defaultLogStreamSubscribers caps concurrent /logs/stream clients when
+	// LOG_STREAM_MAX_SUBSCRIBERS is unset.
+	defaultLogStreamSubscribers = 10
+	// logStreamPingInterval keeps idle streams alive through proxies.
+	logStreamPingInterval = 30 * time.Second
+	logStreamWriteTimeout = 10 * time.Second
+)
+
+var logStreamUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 4096,
+	// Browsers can't send the bearer token on a WebSocket handshake, so
+	// the origin isn't a meaningful check here; AUTH_TOKEN still applies
+	// to non-browser clients.
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// getLogStreamSubscribers reads LOG_STREAM_MAX_SUBSCRIBERS (default 10).
+func getLogStreamSubscribers() int {
+	if val := os.Getenv("LOG_STREAM_MAX_SUBSCRIBERS"); val != "" {
+		if n, err := strconv.Atoi(val); err == nil && n > 0 {
+			return n
+		}
+		slog.Warn("invalid LOG_STREAM_MAX_SUBSCRIBERS, using default", "value", val, "default", defaultLogStreamSubscribers)
+	}
+	return defaultLogStreamSubscribers
+}
+
+// logStreamHandler upgrades to a WebSocket and sends each new log entry as
+// a JSON text message. ?backlog=N first replays the last N buffered entries
+// and ?level= drops entries below that level.
+func logStreamHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if logBuffer == nil {
+		writeError(w, http.StatusNotFound, "log buffer is disabled (LOG_BUFFER_SIZE=0)")
+		return
+	}
+
+	backlog := 0
+	if val := query.Get("backlog"); val != "" {
+		n, err := strconv.Atoi(val)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, "backlog must be a non-negative number")
+			return
+		}
+		backlog = n
+	}
+	minLevel := slog.LevelDebug
+	if val := query.Get("level"); val != "" {
+		if err := minLevel.UnmarshalText([]byte(val)); err != nil {
+			writeError(w, http.StatusBadRequest, "level must be one of debug, info, warn, error")
+			return
+		}
+	}
+
+	entries, buffered, unsubscribe, err := logBuffer.subscribe(getLogStreamSubscribers())
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	defer unsubscribe()
+
+	conn, err := logStreamUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade has already written an error response.
+		return
+	}
+	defer conn.Close()
+
+	// Read in the background so close frames and pongs are processed; the
+	// read fails once the client goes away.
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	send := func(entry LogEntry) error {
+		if entry.level < minLevel {
+			return nil
+		}
+		conn.SetWriteDeadline(time.Now().Add(logStreamWriteTimeout))
+		return conn.WriteJSON(entry)
+	}
+
+	if backlog > 0 {
+		var replay []LogEntry
+		for _, entry := range buffered {
+			if entry.level >= minLevel {
+				replay = append(replay, entry)
+			}
+		}
+		if len(replay) > backlog {
+			replay = replay[len(replay)-backlog:]
+		}
+		for _, entry := range replay {
+			if err := send(entry); err != nil {
+				return
+			}
+		}
+	}
+
+	ping := time.NewTicker(logStreamPingInterval)
+	defer ping.Stop()
+	for {
+		select {
+		case entry := <-entries:
+			if err := send(entry); err != nil {
+				return
+			}
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(logStreamWriteTimeout)); err != nil {
+				return
+			}
+		case <-closed:
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
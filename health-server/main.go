@@ -5,32 +5,65 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
-	"os/exec"
+	"os/signal"
 	"runtime"
+	"sort"
+	"strconv"
 	"strings"
+	"syscall"
 	"time"
 )
 
 type HealthResponse struct {
-	Status    string `json:"status"`
-	Timestamp string `json:"timestamp"`
-	Container string `json:"container"`
-	Runtime   string `json:"runtime,omitempty"`
+	Status         string     `json:"status"`
+	Timestamp      string     `json:"timestamp"`
+	StartTime      string     `json:"start_time"`
+	UptimeSeconds  float64    `json:"uptime_seconds"`
+	Name           string     `json:"name,omitempty"`
+	Container      string     `json:"container"`
+	Runtime        string     `json:"runtime,omitempty"`
+	RuntimeVersion string     `json:"runtime_version,omitempty"`
+	Disk           *DiskCheck `json:"disk,omitempty"`
+	Reasons        []string   `json:"reasons,omitempty"`
 }
 
 type InfoResponse struct {
-	Service     string            `json:"service"`
-	Description string            `json:"description"`
-	Container   string            `json:"container"`
-	Runtime     string            `json:"runtime"`
-	Endpoints   map[string]string `json:"endpoints"`
-	Scripts     map[string]string `json:"scripts"`
-	Timestamp   string            `json:"timestamp"`
+	Service        string            `json:"service"`
+	Description    string            `json:"description"`
+	Name           string            `json:"name,omitempty"`
+	Container      string            `json:"container"`
+	ContainerKnown bool              `json:"container_type_known"`
+	Runtime        string            `json:"runtime"`
+	RuntimeVersion string            `json:"runtime_version"`
+	Runtimes       []string          `json:"runtimes"`
+	Endpoints      map[string]string `json:"endpoints"`
+	Scripts        map[string]string `json:"scripts"`
+	Timestamp      string            `json:"timestamp"`
+}
+
+type NotFoundResponse struct {
+	Error string `json:"error"`
+	Path  string `json:"path"`
+}
+
+// knownContainerTypes are the DEBUG_CONTAINER_TYPE values the image and
+// typical app specs use. Anything else is still honored but flagged, since
+// it's usually a typo.
+var knownContainerTypes = map[string]bool{
+	"debug":        true,
+	"debug-python": true,
+	"debug-node":   true,
+	"sidecar":      true,
+	"init":         true,
+	"worker":       true,
+	"job":          true,
 }
 
 func getContainerType() string {
@@ -40,114 +73,408 @@ func getContainerType() string {
 	return "debug"
 }
 
-func getRuntimeType() string {
-	if val := os.Getenv("DEBUG_RUNTIME"); val != "" {
+// getContainerName identifies this replica: DEBUG_CONTAINER_NAME, or the
+// hostname (the pod name on App Platform) when unset.
+func getContainerName() string {
+	if val := os.Getenv("DEBUG_CONTAINER_NAME"); val != "" {
 		return val
 	}
-	// Auto-detect
-	if _, err := exec.LookPath("node"); err == nil {
-		return "node"
+	hostname, _ := os.Hostname()
+	return hostname
+}
+
+// containerTypeKnown reports whether getContainerType returns one of
+// knownContainerTypes.
+func containerTypeKnown() bool {
+	return knownContainerTypes[getContainerType()]
+}
+
+// warnUnknownContainerType logs a startup warning for an unrecognized
+// DEBUG_CONTAINER_TYPE.
+func warnUnknownContainerType() {
+	if containerTypeKnown() {
+		return
 	}
-	if _, err := exec.LookPath("python3"); err == nil {
-		return "python"
+	known := make([]string, 0, len(knownContainerTypes))
+	for containerType := range knownContainerTypes {
+		known = append(known, containerType)
 	}
-	return "unknown"
+	sort.Strings(known)
+	slog.Warn("unrecognized DEBUG_CONTAINER_TYPE", "value", getContainerType(), "known", strings.Join(known, ","))
+}
+
+// getEnvDuration reads a duration from the environment. Values may be Go
+// durations ("15s") or a bare number of seconds ("15").
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	val := os.Getenv(key)
+	if val == "" {
+		return fallback
+	}
+	if secs, err := strconv.Atoi(val); err == nil {
+		return time.Duration(secs) * time.Second
+	}
+	d, err := time.ParseDuration(val)
+	if err != nil {
+		slog.Warn("invalid duration, using default", "key", key, "value", val, "default", fallback.String())
+		return fallback
+	}
+	return d
+}
+
+// allowGetOrHead rejects any method other than GET and HEAD with 405 and
+// reports whether the handler should continue.
+func allowGetOrHead(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		return true
+	}
+	w.Header().Set("Allow", "GET, HEAD")
+	writeError(w, http.StatusMethodNotAllowed, "method "+r.Method+" not allowed")
+	return false
 }
 
 func healthHandler(w http.ResponseWriter, r *http.Request) {
+	if !allowGetOrHead(w, r) {
+		return
+	}
+	runtimeType := getRuntimeType()
+	if r.URL.Query().Get("refresh") == "true" {
+		runtimeType = refreshRuntimeType()
+	}
+	healthy, reasons := health.status()
+	status := http.StatusOK
+	now := time.Now()
 	response := HealthResponse{
-		Status:    "healthy",
-		Timestamp: time.Now().UTC().Format(time.RFC3339),
-		Container: getContainerType(),
-		Runtime:   getRuntimeType(),
+		Status:         "healthy",
+		Timestamp:      now.UTC().Format(time.RFC3339),
+		StartTime:      startTime.UTC().Format(time.RFC3339),
+		UptimeSeconds:  now.Sub(startTime).Seconds(),
+		Name:           getContainerName(),
+		Container:      getContainerType(),
+		Runtime:        runtimeType,
+		RuntimeVersion: getRuntimeVersion(),
+	}
+	if disk := checkDiskSpace(); disk != nil {
+		response.Disk = disk
+		if disk.Low {
+			healthy = false
+			reasons = append(reasons, fmt.Sprintf("disk: %s has %.1f%% free (minimum %g%%)", disk.Path, disk.FreePct, disk.MinFreePct))
+		}
+	}
+	if !healthy {
+		response.Status = "unhealthy"
+		response.Reasons = reasons
+		status = http.StatusServiceUnavailable
+	}
+	writeNegotiated(w, r, status, response)
+}
+
+// healthzAlias is the Kubernetes-style liveness path served alongside
+// HEALTH_PATH.
+const healthzAlias = "/healthz"
+
+// healthPaths are the paths healthHandler is served on, HEALTH_PATH first,
+// as set by configureHealthPaths.
+var healthPaths = []string{"/health", healthzAlias}
+
+// configureHealthPaths returns the paths healthHandler is served on: HEALTH_PATH
+// (default /health) plus the /healthz alias. It records them in healthPaths
+// and updates the unauthenticated paths to match.
+func configureHealthPaths() []string {
+	healthPath := os.Getenv("HEALTH_PATH")
+	if healthPath == "" {
+		healthPath = "/health"
+	}
+	if !strings.HasPrefix(healthPath, "/") {
+		healthPath = "/" + healthPath
+	}
+
+	paths := []string{healthPath}
+	if healthPath != healthzAlias {
+		paths = append(paths, healthzAlias)
 	}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+
+	delete(unauthenticatedPaths, "/health")
+	for _, path := range paths {
+		unauthenticatedPaths[path] = true
+	}
+	healthPaths = paths
+	return paths
+}
+
+// notFoundHandler answers requests for paths no handler is registered for.
+func notFoundHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusNotFound, NotFoundResponse{Error: "not found", Path: r.URL.Path})
 }
 
 func infoHandler(w http.ResponseWriter, r *http.Request) {
+	// "/" is the mux's catch-all; only the root itself serves the info page.
+	if r.URL.Path != "/" {
+		notFoundHandler(w, r)
+		return
+	}
+	if !allowGetOrHead(w, r) {
+		return
+	}
 	response := InfoResponse{
-		Service:     "do-app-debug-container",
-		Description: "Debug container for DigitalOcean App Platform troubleshooting",
-		Container:   getContainerType(),
-		Runtime:     getRuntimeType(),
-		Endpoints: map[string]string{
-			"/":       "This info page",
-			"/health": "Health check endpoint",
-		},
-		Scripts: map[string]string{
-			"/app/scripts/diagnose.sh":          "Full system diagnostic report",
-			"/app/scripts/test-db.sh":           "Database connectivity test (postgres|mysql|redis|mongodb|kafka|opensearch)",
-			"/app/scripts/test-connectivity.sh": "Network connectivity test",
-		},
-		Timestamp: time.Now().UTC().Format(time.RFC3339),
-	}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
-}
-
-func printStartupBanner(port string, runtimeType string) {
-	banner := `
+		Service:        "do-app-debug-container",
+		Description:    "Debug container for DigitalOcean App Platform troubleshooting",
+		Name:           getContainerName(),
+		Container:      getContainerType(),
+		ContainerKnown: containerTypeKnown(),
+		Runtime:        getRuntimeType(),
+		RuntimeVersion: getRuntimeVersion(),
+		Runtimes:       getRuntimeInfo().Detected,
+		Endpoints:      routeDescriptions(),
+		Scripts:        advertisedScripts,
+		Timestamp:      time.Now().UTC().Format(time.RFC3339),
+	}
+	writeNegotiated(w, r, http.StatusOK, response)
+}
+
+// printStartupBanner writes the human-readable banner to stdout. It lists the
+// HTTP endpoints and the database checks enabled by the current environment.
+// Set PRINT_BANNER=false to suppress it.
+func printStartupBanner(scheme string, addr string, runtimeType string) {
+	if os.Getenv("PRINT_BANNER") == "false" {
+		return
+	}
+
+	const rule = "  ─────────────────────────────────────────────────────────────────────────────\n"
+	var b strings.Builder
+	b.WriteString(`
 ================================================================================
   DigitalOcean App Platform Debug Container
 ================================================================================
 
-  Runtime: %s
-  Health Server: http://0.0.0.0:%s
+`)
+	runtimeDisplay := strings.ToUpper(runtimeType)
+	if runtimeType == "node" {
+		runtimeDisplay = "Node.js"
+	} else if runtimeType == "python" {
+		runtimeDisplay = "Python"
+	}
+	fmt.Fprintf(&b, "  Container: %s (%s)\n", getContainerName(), getContainerType())
+	fmt.Fprintf(&b, "  Runtime: %s %s\n", runtimeDisplay, getRuntimeVersion())
+	fmt.Fprintf(&b, "  Health Server: %s://%s\n", scheme, addr)
+	if ip, _, _ := egress.cached(); ip != "" {
+		fmt.Fprintf(&b, "  Egress IP: %s\n", ip)
+	} else {
+		b.WriteString("  Egress IP: looking up, logged when found (or GET /egress)\n")
+	}
+
+	b.WriteString("\n  HTTP ENDPOINTS:\n" + rule)
+	for _, route := range sortedRoutes() {
+		fmt.Fprintf(&b, "  %-22s %s\n", route.Path, route.Description)
+	}
+
+	b.WriteString("\n  ACTIVE DATABASE CHECKS:\n" + rule)
+	active := 0
+	for _, check := range dbChecks {
+		if check.Configured() {
+			fmt.Fprintf(&b, "  ✓ %-20s /check/%s\n", check.Name, check.Name)
+			active++
+		}
+	}
+	if active == 0 {
+		b.WriteString("  (none - set DATABASE_URL, REDIS_URL, etc. to enable)\n")
+	}
 
+	b.WriteString(`
   AVAILABLE DIAGNOSTIC SCRIPTS:
-  ─────────────────────────────────────────────────────────────────────────────
-  /app/scripts/diagnose.sh              Full system diagnostic report
+` + rule + `  /app/scripts/diagnose.sh              Full system diagnostic report
   /app/scripts/test-db.sh <type>        Database connectivity test
                                         Types: postgres, mysql, redis, mongodb,
                                                kafka, opensearch
   /app/scripts/test-connectivity.sh     Network connectivity test
 
   QUICK COMMANDS:
-  ─────────────────────────────────────────────────────────────────────────────
-  diagnose.sh                           Run full diagnostics
+` + rule + `  diagnose.sh                           Run full diagnostics
   test-db.sh postgres                   Test PostgreSQL connection
   test-db.sh redis                      Test Redis/Valkey connection
   test-connectivity.sh https://api.com  Test HTTP connectivity
   test-connectivity.sh db.example.com 5432  Test TCP connectivity
 
   ENVIRONMENT VARIABLES FOR DATABASE TESTING:
-  ─────────────────────────────────────────────────────────────────────────────
-  DATABASE_URL      PostgreSQL connection string
+` + rule + `  DATABASE_URL      PostgreSQL connection string
   REDIS_URL         Redis/Valkey connection string
   MONGODB_URI       MongoDB connection string
   KAFKA_BROKERS     Kafka broker addresses
   OPENSEARCH_URL    OpenSearch endpoint
 
   ACCESS SHELL:
-  ─────────────────────────────────────────────────────────────────────────────
-  doctl apps console <app-id> <component-name>
+` + rule + `  doctl apps console <app-id> <component-name>
 
 ================================================================================
-`
-	runtimeDisplay := strings.ToUpper(runtimeType)
-	if runtimeType == "node" {
-		runtimeDisplay = "Node.js"
-	} else if runtimeType == "python" {
-		runtimeDisplay = "Python"
+`)
+	fmt.Print(b.String())
+}
+
+// logStartupSummary emits one structured log line describing the server's
+// configuration so log-based tooling doesn't have to scrape the banner.
+func logStartupSummary(scheme string, bindAddr string, port string) {
+	var endpoints []string
+	for _, route := range sortedRoutes() {
+		endpoints = append(endpoints, route.Path)
+	}
+
+	var checks []string
+	for _, check := range dbChecks {
+		if check.Configured() {
+			checks = append(checks, check.Name)
+		}
 	}
-	fmt.Printf(banner, runtimeDisplay, port)
+
+	slog.Info("startup summary",
+		"runtime", getRuntimeType(),
+		"runtime_version", getRuntimeVersion(),
+		"container_name", getContainerName(),
+		"container_type", getContainerType(),
+		"scheme", scheme,
+		"bind_addr", bindAddr,
+		"port", port,
+		"version", version,
+		"endpoints", endpoints,
+		"enabled_checks", checks,
+	)
+}
+
+// serverHandler wraps mux in the middleware every request goes through.
+func serverHandler(mux http.Handler) http.Handler {
+	return logRequests(limitRequestTime(compressResponses(prettyJSON(instrumentRequests(allowCORS(rateLimit(requireAuth(mux))))))))
 }
 
 func main() {
+	startTime = time.Now()
+	configErr := loadConfigFile()
+	setupLogger()
+	if configErr != nil {
+		slog.Error("failed to load CONFIG_FILE", "error", configErr)
+		os.Exit(1)
+	}
+	if errs := validateNumericEnv(); len(errs) > 0 {
+		for _, err := range errs {
+			slog.Error("invalid environment variable", "error", err)
+		}
+		os.Exit(1)
+	}
+	if err := configureMetricsBuckets(); err != nil {
+		slog.Error("invalid environment variable", "error", err)
+		os.Exit(1)
+	}
+	waitChecks, err := waitForChecks()
+	if err != nil {
+		slog.Error("invalid environment variable", "error", err)
+		os.Exit(1)
+	}
+
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8080"
 	}
+	bindAddr := os.Getenv("BIND_ADDR")
+	if bindAddr == "" {
+		bindAddr = "0.0.0.0"
+	}
+	addr := net.JoinHostPort(bindAddr, port)
+	if path := socketPath(); path != "" {
+		addr = "unix:" + path
+	}
 
-	runtimeType := getRuntimeType()
-	printStartupBanner(port, runtimeType)
+	serverTLS, err := getTLSSettings()
+	if err != nil {
+		slog.Error("failed to configure TLS", "error", err)
+		os.Exit(1)
+	}
+	scheme := "http"
+	if serverTLS != nil {
+		scheme = "https"
+	}
+
+	routes, err = buildRoutes(configureHealthPaths())
+	if err != nil {
+		slog.Error("invalid configuration", "error", err)
+		os.Exit(1)
+	}
+	configureTrustedProxies()
+	warnUnknownContainerType()
+	advertisedScripts = loadScripts()
+	runtimeType := refreshRuntimeType()
+	printStartupBanner(scheme, addr, runtimeType)
+	logStartupSummary(scheme, bindAddr, port)
+
+	mux := http.NewServeMux()
+	registerRoutes(mux)
+
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           serverHandler(mux),
+		ReadHeaderTimeout: getEnvDuration("SERVER_READ_HEADER_TIMEOUT", 5*time.Second),
+		ReadTimeout:       getEnvDuration("SERVER_READ_TIMEOUT", 15*time.Second),
+		WriteTimeout:      getEnvDuration("SERVER_WRITE_TIMEOUT", 60*time.Second),
+		IdleTimeout:       getEnvDuration("SERVER_IDLE_TIMEOUT", 120*time.Second),
+	}
+	if serverTLS != nil {
+		server.TLSConfig = serverTLS.Config
+	}
 
-	http.HandleFunc("/", infoHandler)
-	http.HandleFunc("/health", healthHandler)
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
 
-	log.Printf("Health server starting on port %s (Go %s)", port, runtime.Version())
-	if err := http.ListenAndServe(":"+port, nil); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+	if interval := getPollInterval(); interval > 0 {
+		startPoller(ctx, interval)
+	}
+
+	ln, err := listen(addr)
+	if err != nil {
+		slog.Error("failed to start server", "error", err)
+		os.Exit(1)
+	}
+
+	pprofServer := startPprofServer()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		slog.Info("health server starting", "addr", addr, "scheme", scheme, "version", version, "go_version", runtime.Version())
+		var err error
+		if serverTLS != nil {
+			err = server.ServeTLS(ln, serverTLS.CertFile, serverTLS.KeyFile)
+		} else {
+			err = server.Serve(ln)
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+		}
+	}()
+	go logEgressIP()
+
+	// /health is served while waiting; /ready fails until the wait ends.
+	if err := waitForDependencies(ctx, waitChecks); err != nil && ctx.Err() == nil {
+		if os.Getenv("WAIT_FAIL") == "true" {
+			slog.Error("startup wait failed", "error", err)
+			os.Exit(1)
+		}
+		slog.Warn("startup wait timed out, continuing", "error", err)
+	}
+	startupComplete.Store(true)
+	startWatchdog(ctx, ln, scheme)
+
+	select {
+	case err := <-serverErr:
+		slog.Error("failed to start server", "error", err)
+		os.Exit(1)
+	case <-ctx.Done():
+	}
+
+	shutdownTimeout := getEnvDuration("SHUTDOWN_TIMEOUT", 10*time.Second)
+	slog.Info("health server shutting down", "grace_period", shutdownTimeout.String())
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		slog.Warn("graceful shutdown incomplete", "error", err)
+	}
+	if pprofServer != nil {
+		pprofServer.Close()
 	}
+	closeClients()
+	slog.Info("health server stopped")
 }
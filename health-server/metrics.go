@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	httpRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "debug_container_http_requests_total",
+		Help: "HTTP requests handled, by endpoint and status code.",
+	}, []string{"endpoint", "status"})
+
+	httpRequestDuration = newRequestDurationHistogram(prometheus.DefBuckets)
+
+	dependencyUp = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "debug_container_dependency_up",
+		Help: "Result of the most recent check per database (1 = reachable, 0 = unreachable).",
+	}, []string{"database"})
+
+	containerInfo = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "debug_container_info",
+		Help: "Debug container metadata; always 1.",
+	}, []string{"runtime", "container"})
+)
+
+func init() {
+	prometheus.MustRegister(httpRequestsTotal, httpRequestDuration, dependencyUp, containerInfo)
+}
+
+func newRequestDurationHistogram(buckets []float64) *prometheus.HistogramVec {
+	return prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "debug_container_http_request_duration_seconds",
+		Help:    "Handler latency in seconds, by endpoint.",
+		Buckets: buckets,
+	}, []string{"endpoint"})
+}
+
+// parseBuckets reads a comma-separated list of upper bounds in seconds,
+// which must be positive and distinct. They are returned in ascending order.
+func parseBuckets(raw string) ([]float64, error) {
+	var buckets []float64
+	for _, field := range strings.Split(raw, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		bound, err := strconv.ParseFloat(field, 64)
+		if err != nil || bound <= 0 || math.IsInf(bound, 0) {
+			return nil, fmt.Errorf("METRICS_BUCKETS must be positive numbers of seconds separated by commas, got %q", field)
+		}
+		buckets = append(buckets, bound)
+	}
+	if len(buckets) == 0 {
+		return nil, fmt.Errorf("METRICS_BUCKETS must list at least one bucket")
+	}
+	sort.Float64s(buckets)
+	for i := 1; i < len(buckets); i++ {
+		if buckets[i] == buckets[i-1] {
+			return nil, fmt.Errorf("METRICS_BUCKETS lists %v more than once", buckets[i])
+		}
+	}
+	return buckets, nil
+}
+
+// configureMetricsBuckets replaces the handler-latency histogram with one
+// using METRICS_BUCKETS, when set. It runs before the server starts, so no
+// observations are lost.
+func configureMetricsBuckets() error {
+	raw := os.Getenv("METRICS_BUCKETS")
+	if raw == "" {
+		return nil
+	}
+	buckets, err := parseBuckets(raw)
+	if err != nil {
+		return err
+	}
+	prometheus.Unregister(httpRequestDuration)
+	httpRequestDuration = newRequestDurationHistogram(buckets)
+	return prometheus.Register(httpRequestDuration)
+}
+
+// recordCheckResult publishes the latest check outcome for a database.
+func recordCheckResult(database string, ok bool) {
+	val := 0.0
+	if ok {
+		val = 1
+	}
+	dependencyUp.WithLabelValues(database).Set(val)
+}
+
+// setContainerInfo replaces the labels on the info gauge.
+func setContainerInfo(runtimeType, containerType string) {
+	containerInfo.Reset()
+	containerInfo.WithLabelValues(runtimeType, containerType).Set(1)
+}
+
+// endpointLabel names the endpoint that served r for /metrics and /stats:
+// its registered route pattern, so unknown paths can't blow up label
+// cardinality, or "unmatched" for paths that matched no route or fell
+// through to the "/" catch-all.
+func endpointLabel(r *http.Request) string {
+	if r.Pattern == "" || (r.Pattern == "/" && r.URL.Path != "/") {
+		return "unmatched"
+	}
+	return r.Pattern
+}
+
+// instrumentRequests records request counts and latency, for /metrics and
+// /stats. It runs inside limitRequestTime, whose TimeoutHandler hands the
+// mux a copy of the request: only that copy gets the pattern set.
+func instrumentRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		endpoint := endpointLabel(r)
+		countRequest(endpoint)
+		httpRequestsTotal.WithLabelValues(endpoint, strconv.Itoa(rec.status)).Inc()
+		httpRequestDuration.WithLabelValues(endpoint).Observe(time.Since(start).Seconds())
+	})
+}
+
+func metricsHandler() http.Handler {
+	return promhttp.Handler()
+}
@@ -0,0 +1,383 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type DNSResult struct {
+	Host          string   `json:"host"`
+	Type          string   `json:"type"`
+	IPVersion     string   `json:"ip_version,omitempty"`
+	Records       []string `json:"records"`
+	CNAME         string   `json:"cname,omitempty"`
+	LatencyMs     float64  `json:"latency_ms"`
+	Resolvers     []string `json:"resolvers"`
+	Error         string   `json:"error,omitempty"`
+	ErrorCategory string   `json:"error_category,omitempty"`
+}
+
+// systemResolvers lists the nameservers from /etc/resolv.conf.
+func systemResolvers() []string {
+	resolvers := []string{}
+	f, err := os.Open("/etc/resolv.conf")
+	if err != nil {
+		return resolvers
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "nameserver" {
+			resolvers = append(resolvers, fields[1])
+		}
+	}
+	return resolvers
+}
+
+// resolveDNS looks up host for the given record type. The default type
+// returns A/AAAA addresses, only A or only AAAA when ctx carries an
+// ?ipversion=, along with the canonical name.
+func resolveDNS(ctx context.Context, host, recordType string) DNSResult {
+	result := DNSResult{
+		Host:      host,
+		Type:      recordType,
+		IPVersion: ipVersion(ctx),
+		Records:   []string{},
+		Resolvers: systemResolvers(),
+	}
+	resolver := net.DefaultResolver
+
+	start := time.Now()
+	var err error
+	switch recordType {
+	case "a":
+		var addrs []net.IP
+		addrs, err = resolver.LookupIP(ctx, "ip"+ipVersion(ctx), host)
+		for _, addr := range addrs {
+			result.Records = append(result.Records, addr.String())
+		}
+		if err == nil {
+			if cname, cerr := resolver.LookupCNAME(ctx, host); cerr == nil && strings.TrimSuffix(cname, ".") != host {
+				result.CNAME = cname
+			}
+		}
+	case "txt":
+		result.Records, err = resolver.LookupTXT(ctx, host)
+	case "mx":
+		var mxs []*net.MX
+		mxs, err = resolver.LookupMX(ctx, host)
+		for _, mx := range mxs {
+			result.Records = append(result.Records, fmt.Sprintf("%d %s", mx.Pref, mx.Host))
+		}
+	case "srv":
+		var srvs []*net.SRV
+		_, srvs, err = resolver.LookupSRV(ctx, "", "", host)
+		for _, srv := range srvs {
+			result.Records = append(result.Records, fmt.Sprintf("%d %d %d %s", srv.Priority, srv.Weight, srv.Port, srv.Target))
+		}
+	default:
+		err = fmt.Errorf("unsupported record type %q (use a, txt, mx, or srv)", recordType)
+	}
+	result.LatencyMs = latencyMs(time.Since(start))
+	if err != nil {
+		result.Error = err.Error()
+		result.ErrorCategory = errorCategory(err)
+	}
+	return result
+}
+
+// dnsHandler serves /dns?host=example.com[&type=txt|mx|srv].
+func dnsHandler(w http.ResponseWriter, r *http.Request) {
+	host := r.URL.Query().Get("host")
+	if host == "" {
+		writeError(w, http.StatusBadRequest, "missing host parameter")
+		return
+	}
+	recordType := strings.ToLower(r.URL.Query().Get("type"))
+	if recordType == "" {
+		recordType = "a"
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), checkTimeout(r.Context()))
+	defer cancel()
+	result := resolveDNS(ctx, host, recordType)
+	writeCheckResult(w, result.Error == "", result)
+}
+
+type DBResolutionResult struct {
+	Host        string   `json:"host"`
+	ResolvedIPs []string `json:"resolved_ips"`
+	PublicIPs   []string `json:"public_ips,omitempty"`
+	IsPrivate   bool     `json:"is_private"`
+	Note        string   `json:"note,omitempty"`
+	Error       string   `json:"error,omitempty"`
+}
+
+// checkDBResolution resolves the host in dsn and reports whether every
+// address is private (RFC 1918, IPv6 ULA or loopback). Over VPC a managed database
+// resolves to its private address; a public one means traffic is leaving
+// the VPC.
+func checkDBResolution(ctx context.Context, dsn string) DBResolutionResult {
+	result := DBResolutionResult{ResolvedIPs: []string{}}
+	params, err := postgresDSNParams(dsn)
+	if err != nil || params["host"] == "" {
+		result.Error = errMalformedConnString.Error()
+		return result
+	}
+	result.Host = params["host"]
+
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", result.Host)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.IsPrivate = len(addrs) > 0
+	for _, addr := range addrs {
+		addr = addr.Unmap()
+		result.ResolvedIPs = append(result.ResolvedIPs, addr.String())
+		if !addr.IsPrivate() && !addr.IsLoopback() {
+			result.IsPrivate = false
+			result.PublicIPs = append(result.PublicIPs, addr.String())
+		}
+	}
+	if !result.IsPrivate {
+		result.Note = "host resolves to a public address — use the database's private (VPC) hostname to keep traffic off the public internet"
+	}
+	return result
+}
+
+// dbResolutionHandler serves /check/dns-over-db for the host in
+// DATABASE_URL, answering 503 when it doesn't resolve to private addresses.
+func dbResolutionHandler(w http.ResponseWriter, r *http.Request) {
+	dsn := os.Getenv("DATABASE_URL")
+	if dsn == "" {
+		writeCheckResult(w, false, DBResolutionResult{ResolvedIPs: []string{}, Error: "DATABASE_URL is not set"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), checkTimeout(r.Context()))
+	defer cancel()
+	result := checkDBResolution(ctx, dsn)
+	writeCheckResult(w, result.Error == "" && result.IsPrivate, result)
+}
+
+// maxProbeTimeout caps caller-supplied ?timeout= values.
+const maxProbeTimeout = 30 * time.Second
+
+type TCPResult struct {
+	Host          string  `json:"host"`
+	Port          string  `json:"port"`
+	IPVersion     string  `json:"ip_version,omitempty"`
+	Reachable     bool    `json:"reachable"`
+	LatencyMs     float64 `json:"latency_ms"`
+	Error         string  `json:"error,omitempty"`
+	ErrorCategory string  `json:"error_category,omitempty"`
+}
+
+// probeTimeout reads ?timeout= with timeoutParam, defaulting to 5s.
+func probeTimeout(r *http.Request) (time.Duration, error) {
+	return timeoutParam(r, 5*time.Second)
+}
+
+// timeoutParam reads ?timeout= (a Go duration or seconds), returning def when
+// it is absent. The result never exceeds maxProbeTimeout, and ends a second
+// before the request's REQUEST_TIMEOUT deadline, past which the handler's
+// answer would be replaced by a generic 503.
+func timeoutParam(r *http.Request, def time.Duration) (time.Duration, error) {
+	d := def
+	if val := r.URL.Query().Get("timeout"); val != "" {
+		var err error
+		d, err = time.ParseDuration(val)
+		if err != nil {
+			secs, serr := strconv.ParseFloat(val, 64)
+			if serr != nil {
+				return 0, fmt.Errorf("invalid timeout %q: must be a duration such as 3s or a number of seconds", val)
+			}
+			d = time.Duration(secs * float64(time.Second))
+		}
+		if d <= 0 {
+			return 0, fmt.Errorf("timeout must be positive")
+		}
+	}
+	d = min(d, maxProbeTimeout)
+	if deadline, ok := r.Context().Deadline(); ok {
+		d = min(d, time.Until(deadline)-time.Second)
+	}
+	return d, nil
+}
+
+// dialTCP reports whether a TCP connection to host:port succeeds, over the IP
+// version ctx asks for, if any.
+func dialTCP(ctx context.Context, host, port string, timeout time.Duration) TCPResult {
+	result := TCPResult{Host: host, Port: port, IPVersion: ipVersion(ctx)}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	start := time.Now()
+	conn, err := dialOutbound(ctx, "tcp", net.JoinHostPort(host, port))
+	result.LatencyMs = latencyMs(time.Since(start))
+	if err != nil {
+		result.Error = err.Error()
+		result.ErrorCategory = errorCategory(err)
+		return result
+	}
+	conn.Close()
+	result.Reachable = true
+	return result
+}
+
+// tcpHandler serves /tcp?host=db.example.com&port=5432[&timeout=2s].
+func tcpHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	host, port := query.Get("host"), query.Get("port")
+	if host == "" || port == "" {
+		writeError(w, http.StatusBadRequest, "host and port parameters are required")
+		return
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		writeError(w, http.StatusBadRequest, "port must be a number between 1 and 65535")
+		return
+	}
+	timeout, err := probeTimeout(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	result := dialTCP(r.Context(), host, port, timeout)
+	writeCheckResult(w, result.Reachable, result)
+}
+
+// maxRedirects limits how many redirects /http follows.
+const maxRedirects = 10
+
+type HTTPTimings struct {
+	DNSMs          float64 `json:"dns_ms"`
+	ConnectMs      float64 `json:"connect_ms"`
+	TLSHandshakeMs float64 `json:"tls_handshake_ms"`
+	FirstByteMs    float64 `json:"first_byte_ms"`
+	TotalMs        float64 `json:"total_ms"`
+}
+
+type HTTPRedirect struct {
+	URL        string `json:"url"`
+	StatusCode int    `json:"status_code"`
+}
+
+type HTTPProbeResult struct {
+	URL           string         `json:"url"`
+	FinalURL      string         `json:"final_url,omitempty"`
+	StatusCode    int            `json:"status_code,omitempty"`
+	Redirects     []HTTPRedirect `json:"redirects"`
+	Timings       HTTPTimings    `json:"timings"`
+	Error         string         `json:"error,omitempty"`
+	ErrorCategory string         `json:"error_category,omitempty"`
+}
+
+// tracedGet performs a single GET and records the phase timings of the
+// request, similar to curl -w.
+func tracedGet(ctx context.Context, client *http.Client, target string) (*http.Response, HTTPTimings, error) {
+	var timings HTTPTimings
+	var dnsStart, connectStart, tlsStart time.Time
+	start := time.Now()
+	trace := &httptrace.ClientTrace{
+		DNSStart:             func(httptrace.DNSStartInfo) { dnsStart = time.Now() },
+		DNSDone:              func(httptrace.DNSDoneInfo) { timings.DNSMs = latencyMs(time.Since(dnsStart)) },
+		ConnectStart:         func(string, string) { connectStart = time.Now() },
+		ConnectDone:          func(string, string, error) { timings.ConnectMs = latencyMs(time.Since(connectStart)) },
+		TLSHandshakeStart:    func() { tlsStart = time.Now() },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { timings.TLSHandshakeMs = latencyMs(time.Since(tlsStart)) },
+		GotFirstResponseByte: func() { timings.FirstByteMs = latencyMs(time.Since(start)) },
+	}
+
+	req, err := http.NewRequestWithContext(httptrace.WithClientTrace(ctx, trace), http.MethodGet, target, nil)
+	if err != nil {
+		return nil, timings, err
+	}
+	req.Header.Set("User-Agent", "do-app-debug-container")
+	resp, err := client.Do(req)
+	if err != nil {
+		timings.TotalMs = latencyMs(time.Since(start))
+		return nil, timings, err
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	timings.TotalMs = latencyMs(time.Since(start))
+	return resp, timings, nil
+}
+
+// probeHTTP fetches target, following redirects by hand so each hop is
+// recorded. Timings describe the final hop; total_ms covers the whole chain.
+func probeHTTP(ctx context.Context, target string) HTTPProbeResult {
+	result := HTTPProbeResult{URL: target, Redirects: []HTTPRedirect{}}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialOutbound
+	client := &http.Client{
+		Transport:     transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	defer client.CloseIdleConnections()
+
+	start := time.Now()
+	current := target
+	for hop := 0; ; hop++ {
+		resp, timings, err := tracedGet(ctx, client, current)
+		result.Timings = timings
+		if err != nil {
+			result.Error = err.Error()
+			result.ErrorCategory = errorCategory(err)
+			break
+		}
+		result.StatusCode = resp.StatusCode
+		result.FinalURL = current
+
+		location := resp.Header.Get("Location")
+		if resp.StatusCode < 300 || resp.StatusCode >= 400 || location == "" {
+			break
+		}
+		if hop == maxRedirects {
+			result.Error = fmt.Sprintf("stopped after %d redirects", maxRedirects)
+			break
+		}
+		next, err := resp.Request.URL.Parse(location)
+		if err != nil {
+			result.Error = "invalid redirect location: " + err.Error()
+			break
+		}
+		result.Redirects = append(result.Redirects, HTTPRedirect{URL: current, StatusCode: resp.StatusCode})
+		current = next.String()
+	}
+	result.Timings.TotalMs = latencyMs(time.Since(start))
+	return result
+}
+
+// httpProbeHandler serves /http?url=https://api.example.com[&timeout=10s].
+func httpProbeHandler(w http.ResponseWriter, r *http.Request) {
+	target := r.URL.Query().Get("url")
+	u, err := url.Parse(target)
+	if target == "" || err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		writeError(w, http.StatusBadRequest, "url parameter must be an absolute http(s) URL")
+		return
+	}
+	timeout, err := probeTimeout(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+	result := probeHTTP(ctx, target)
+	writeCheckResult(w, result.Error == "", result)
+}
@@ -0,0 +1,390 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// apiParam is a query parameter accepted by an endpoint.
+type apiParam struct {
+	Name        string
+	Description string
+	Required    bool
+}
+
+// apiOperation describes an endpoint for /openapi.json. Response is a zero
+// value of the type the handler encodes; its schema is derived from the
+// struct's json tags so the document can't drift from the handlers.
+type apiOperation struct {
+	Methods     []string // defaults to GET
+	Params      []apiParam
+	Request     any
+	Response    any
+	ContentType string // for non-JSON responses
+	Check       bool   // answers 503 when the check fails
+}
+
+var formatParam = apiParam{Name: "format", Description: "Response format: json, yaml or text"}
+
+// healthRouteDescription describes every path the liveness check is served
+// on.
+const healthRouteDescription = "Health check endpoint (?refresh=true re-detects runtime)"
+
+// apiOperations documents every route in the registry, keyed by path.
+var apiOperations = map[string]apiOperation{
+	"/":       {Params: []apiParam{formatParam}, Response: InfoResponse{}},
+	"/health": {Params: []apiParam{{Name: "refresh", Description: "true re-detects the runtime"}, formatParam}, Response: HealthResponse{}, Check: true},
+	"/ready":  {Response: ReadyResponse{}, Check: true},
+	"/routes": {Params: []apiParam{formatParam}, Response: []Route{}},
+
+	"/check/postgres": {Response: []PostgresCheckResult{}, Check: true},
+	"/check/postgres/locks": {
+		Params: []apiParam{
+			{Name: "limit", Description: "Maximum blocked/blocking pairs to return (default 50, max 500)"},
+			{Name: "redact", Description: "true replaces literals in query text with ?"},
+		},
+		Response: PostgresLocksResult{},
+		Check:    true,
+	},
+	"/check/postgres/pool":      {Response: PostgresPoolResult{}, Check: true},
+	"/check/postgres/ssl":       {Response: PostgresSSLResult{}, Check: true},
+	"/check/dns-over-db":        {Response: DBResolutionResult{}, Check: true},
+	"/check/redis":              {Response: RedisCheckResult{}, Check: true},
+	"/check/redis/info":         {Response: RedisInfoResult{}, Check: true},
+	"/check/redis/cluster":      {Response: RedisClusterResult{}, Check: true},
+	"/check/redis/latency":      {Response: RedisLatencyResult{}, Check: true},
+	"/check/mysql":              {Response: MySQLCheckResult{}, Check: true},
+	"/check/mysql/status":       {Response: MySQLStatusResult{}, Check: true},
+	"/check/mongodb":            {Response: MongoDBCheckResult{}, Check: true},
+	"/check/mongodb/rs":         {Response: MongoDBReplicaSetResult{}, Check: true},
+	"/check/kafka":              {Response: KafkaCheckResult{}, Check: true},
+	"/check/kafka/roundtrip":    {Response: KafkaRoundTripResult{}, Check: true},
+	"/check/opensearch":         {Response: OpenSearchCheckResult{}, Check: true},
+	"/check/opensearch/indices": {Response: OpenSearchIndicesResult{}, Check: true},
+	"/check/tcp-batch":          {Methods: []string{http.MethodPost}, Request: []TCPTarget{}, Response: TCPBatchResponse{}, Check: true},
+	"/check/http-proxy":         {Params: []apiParam{{Name: "url", Description: "URL to request through the proxy (default EGRESS_CHECK_URL)"}}, Response: HTTPProxyResult{}, Check: true},
+	"/check/all":                {Params: []apiParam{{Name: "live", Description: "true re-runs the checks instead of serving cached results"}}, Response: map[string]checkOutcome{}, Check: true},
+	"/check/auto":               {Response: map[string]AutoCheckResult{}, Check: true},
+
+	"/benchmark": {
+		Params: []apiParam{
+			{Name: "target", Description: "Dependency to benchmark: postgres, redis, mysql, mongodb, kafka or opensearch", Required: true},
+			{Name: "n", Description: "Number of sequential runs (default 10, max 100)"},
+		},
+		Response: BenchmarkResult{},
+		Check:    true,
+	},
+	"/pg-query": {Methods: []string{http.MethodPost}, Request: PgQueryRequest{}, Response: PgQueryResult{}},
+	"/file": {
+		Params:      []apiParam{{Name: "path", Description: "Absolute path of a file under VIEWABLE_DIRS", Required: true}},
+		ContentType: "text/plain",
+	},
+	"/ls": {
+		Params:   []apiParam{{Name: "dir", Description: "Absolute path of a directory under VIEWABLE_DIRS", Required: true}},
+		Response: DirListing{},
+	},
+	"/logs": {
+		Params: []apiParam{
+			{Name: "n", Description: "Return only the last n entries"},
+			{Name: "level", Description: "Minimum level: debug, info, warn or error"},
+			formatParam,
+		},
+		Response: LogsResponse{},
+	},
+	"/logs/stream": {
+		Params: []apiParam{
+			{Name: "backlog", Description: "Buffered entries to send before streaming"},
+			{Name: "level", Description: "Minimum level: debug, info, warn or error"},
+		},
+		ContentType: "websocket",
+	},
+	"/self-test":    {Response: SelfTestResponse{}, Check: true},
+	"/openapi.json": {},
+	"/stats":        {Response: StatsResponse{}},
+	"/metrics":      {ContentType: "text/plain"},
+	"/env":          {Params: []apiParam{{Name: "prefix", Description: "Only return variables starting with this prefix"}}, Response: map[string]string{}},
+	"/env/diff":     {Response: EnvDiffResponse{}, Check: true},
+	"/sysinfo":      {Response: SysInfoResponse{}},
+	"/diagnose.json": {
+		Response: DiagnoseResponse{},
+		Check:    true,
+	},
+
+	"/dns": {
+		Params: []apiParam{
+			{Name: "host", Description: "Hostname to resolve", Required: true},
+			{Name: "type", Description: "Record type: a, txt, mx or srv"},
+			{Name: "ipversion", Description: "For type a, return only A (4) or AAAA (6) records"},
+		},
+		Response: DNSResult{},
+		Check:    true,
+	},
+	"/tcp": {
+		Params: []apiParam{
+			{Name: "host", Description: "Host to connect to", Required: true},
+			{Name: "port", Description: "Port to connect to", Required: true},
+			{Name: "timeout", Description: "Dial timeout, e.g. 5s"},
+			{Name: "ipversion", Description: "Connect over IPv4 (4) or IPv6 (6) only; default either"},
+		},
+		Response: TCPResult{},
+		Check:    true,
+	},
+	"/trace": {
+		Params: []apiParam{
+			{Name: "host", Description: "Destination host", Required: true},
+			{Name: "port", Description: "Destination port"},
+			{Name: "max_hops", Description: "Maximum TTL to probe"},
+			{Name: "method", Description: "tcp to probe with TCP SYNs instead of ICMP"},
+		},
+		Response: TraceResult{},
+		Check:    true,
+	},
+	"/http": {Params: []apiParam{{Name: "url", Description: "URL to GET", Required: true}}, Response: HTTPProbeResult{}, Check: true},
+	"/tls": {
+		Params: []apiParam{
+			{Name: "host", Description: "Host to connect to", Required: true},
+			{Name: "port", Description: "Port (default 443)"},
+			{Name: "insecure", Description: "true skips certificate verification"},
+		},
+		Response: TLSInspectResult{},
+		Check:    true,
+	},
+	"/clock":   {Response: ClockResult{}, Check: true},
+	"/egress":  {Response: EgressResponse{}, Check: true},
+	"/version": {Response: VersionResponse{}},
+	"/whoami":  {Response: WhoAmIResponse{}},
+	"/exec": {
+		Params: []apiParam{
+			{Name: "script", Description: "diagnose, test-db or test-connectivity", Required: true},
+			{Name: "arg", Description: "Argument passed to the script (repeatable)"},
+		},
+		ContentType: "text/plain",
+	},
+	"/admin/drain": {
+		Methods:  []string{http.MethodPost},
+		Params:   []apiParam{{Name: "reason", Description: "Reason reported by /ready"}},
+		Response: DrainResponse{},
+	},
+	"/admin/undrain": {Methods: []string{http.MethodPost}, Response: DrainResponse{}},
+	"/unhealthy": {
+		Methods: []string{http.MethodPost, http.MethodDelete},
+		Params:  []apiParam{{Name: "reason", Description: "Reason reported by /health (POST only)"}},
+		Response: struct {
+			Healthy bool     `json:"healthy"`
+			Reasons []string `json:"reasons"`
+		}{},
+	},
+}
+
+type OpenAPIDocument struct {
+	OpenAPI    string                                 `json:"openapi"`
+	Info       OpenAPIInfo                            `json:"info"`
+	Paths      map[string]map[string]OpenAPIOperation `json:"paths"`
+	Components OpenAPIComponents                      `json:"components"`
+}
+
+type OpenAPIInfo struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Version     string `json:"version"`
+}
+
+type OpenAPIOperation struct {
+	Summary     string                     `json:"summary"`
+	Parameters  []OpenAPIParameter         `json:"parameters,omitempty"`
+	RequestBody *OpenAPIBody               `json:"requestBody,omitempty"`
+	Responses   map[string]OpenAPIResponse `json:"responses"`
+}
+
+type OpenAPIParameter struct {
+	Name        string         `json:"name"`
+	In          string         `json:"in"`
+	Description string         `json:"description,omitempty"`
+	Required    bool           `json:"required,omitempty"`
+	Schema      map[string]any `json:"schema"`
+}
+
+type OpenAPIBody struct {
+	Required bool                        `json:"required,omitempty"`
+	Content  map[string]OpenAPIMediaType `json:"content"`
+}
+
+type OpenAPIResponse struct {
+	Description string                      `json:"description"`
+	Content     map[string]OpenAPIMediaType `json:"content,omitempty"`
+}
+
+type OpenAPIMediaType struct {
+	Schema map[string]any `json:"schema"`
+}
+
+type OpenAPIComponents struct {
+	Schemas map[string]map[string]any `json:"schemas"`
+}
+
+// schemaBuilder turns Go types into JSON schemas, collecting named structs
+// into the document's components.
+type schemaBuilder struct {
+	components map[string]map[string]any
+}
+
+func (b *schemaBuilder) schema(t reflect.Type) map[string]any {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return b.schema(t.Elem())
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": b.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": b.schema(t.Elem())}
+	case reflect.Struct:
+		name := t.Name()
+		if name == "" || !isExportedName(name) {
+			return b.structSchema(t)
+		}
+		if _, seen := b.components[name]; !seen {
+			// Reserve the name first so self-referencing types terminate.
+			b.components[name] = nil
+			b.components[name] = b.structSchema(t)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + name}
+	}
+	// interface{} fields hold any JSON value.
+	return map[string]any{}
+}
+
+func (b *schemaBuilder) structSchema(t reflect.Type) map[string]any {
+	properties := make(map[string]any)
+	var required []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = b.schema(field.Type)
+		if !strings.Contains(opts, "omitempty") {
+			required = append(required, name)
+		}
+	}
+	schema := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func isExportedName(name string) bool {
+	return name[0] >= 'A' && name[0] <= 'Z'
+}
+
+// buildOpenAPIDocument describes every registered route.
+func buildOpenAPIDocument() OpenAPIDocument {
+	builder := &schemaBuilder{components: make(map[string]map[string]any)}
+	errorSchema := builder.schema(reflect.TypeOf(ErrorResponse{}))
+	doc := OpenAPIDocument{
+		OpenAPI: "3.0.3",
+		Info: OpenAPIInfo{
+			Title:       "DigitalOcean App Platform debug container",
+			Description: "Health, connectivity and diagnostics endpoints. Every JSON endpoint also accepts ?pretty=true.",
+			Version:     version,
+		},
+		Paths: make(map[string]map[string]OpenAPIOperation),
+	}
+
+	for _, route := range sortedRoutes() {
+		spec, ok := apiOperations[route.Path]
+		if !ok && slices.Contains(healthPaths, route.Path) {
+			spec, ok = apiOperations["/health"]
+		}
+		if !ok {
+			spec = apiOperation{}
+		}
+
+		op := OpenAPIOperation{Summary: route.Description, Responses: make(map[string]OpenAPIResponse)}
+		params := spec.Params
+		if strings.HasPrefix(route.Path, "/check/") {
+			params = append(params,
+				apiParam{Name: "timeout", Description: "Overrides CHECK_TIMEOUT for this request, e.g. 3s or 3; capped at 30s and below REQUEST_TIMEOUT"},
+				apiParam{Name: "ipversion", Description: "Connect over IPv4 (4) or IPv6 (6) only; default either"},
+			)
+		}
+		for _, param := range params {
+			op.Parameters = append(op.Parameters, OpenAPIParameter{
+				Name:        param.Name,
+				In:          "query",
+				Description: param.Description,
+				Required:    param.Required,
+				Schema:      map[string]any{"type": "string"},
+			})
+		}
+		if spec.Request != nil {
+			op.RequestBody = &OpenAPIBody{
+				Required: true,
+				Content:  map[string]OpenAPIMediaType{"application/json": {Schema: builder.schema(reflect.TypeOf(spec.Request))}},
+			}
+		}
+
+		switch {
+		case spec.ContentType == "websocket":
+			op.Responses["101"] = OpenAPIResponse{Description: "Switching to a WebSocket that sends one JSON log entry per message"}
+		case spec.ContentType != "":
+			op.Responses["200"] = OpenAPIResponse{
+				Description: "OK",
+				Content:     map[string]OpenAPIMediaType{spec.ContentType: {Schema: map[string]any{"type": "string"}}},
+			}
+		default:
+			responseSchema := map[string]any{}
+			if spec.Response != nil {
+				responseSchema = builder.schema(reflect.TypeOf(spec.Response))
+			}
+			op.Responses["200"] = OpenAPIResponse{
+				Description: "OK",
+				Content:     map[string]OpenAPIMediaType{"application/json": {Schema: responseSchema}},
+			}
+			if spec.Check {
+				op.Responses["503"] = OpenAPIResponse{
+					Description: "The check failed or its dependency is not configured",
+					Content:     map[string]OpenAPIMediaType{"application/json": {Schema: responseSchema}},
+				}
+			}
+		}
+		op.Responses["4XX"] = OpenAPIResponse{
+			Description: "Invalid request",
+			Content:     map[string]OpenAPIMediaType{"application/json": {Schema: errorSchema}},
+		}
+
+		methods := spec.Methods
+		if len(methods) == 0 {
+			methods = []string{http.MethodGet}
+		}
+		doc.Paths[route.Path] = make(map[string]OpenAPIOperation, len(methods))
+		for _, method := range methods {
+			doc.Paths[route.Path][strings.ToLower(method)] = op
+		}
+	}
+	doc.Components.Schemas = builder.components
+	return doc
+}
+
+// openAPIHandler serves an OpenAPI 3 description of every endpoint.
+func openAPIHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, buildOpenAPIDocument())
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// maxQueryRows caps the rows /pg-query returns; further rows are dropped and
+// the result is marked truncated.
+const maxQueryRows = 500
+
+// maxQueryBodyBytes bounds the size of a /pg-query request body.
+const maxQueryBodyBytes = 64 << 10
+
+// readOnlyStatements are the leading keywords /pg-query accepts.
+var readOnlyStatements = map[string]bool{
+	"SELECT":  true,
+	"EXPLAIN": true,
+	"SHOW":    true,
+}
+
+// explainOptions are the unparenthesized options EXPLAIN accepts before the
+// statement it explains.
+var explainOptions = map[string]bool{
+	"ANALYZE": true,
+	"ANALYSE": true,
+	"VERBOSE": true,
+}
+
+// deniedFunctions matches server functions a READ ONLY transaction doesn't
+// stop: signalling or cancelling other backends, changing server state, and
+// reading server files or reaching other databases.
+var deniedFunctions = regexp.MustCompile(`(?i)\b(pg_terminate_backend|pg_cancel_backend|pg_reload_conf|pg_rotate_logfile|pg_promote|pg_switch_wal|pg_create_restore_point|pg_log_backend_memory_contexts|pg_read_file|pg_read_binary_file|pg_ls_dir|lo_import|lo_export|dblink\w*)\b`)
+
+type PgQueryRequest struct {
+	SQL string `json:"sql"`
+}
+
+type PgQueryResult struct {
+	Columns   []string        `json:"columns"`
+	Rows      [][]interface{} `json:"rows"`
+	RowCount  int             `json:"row_count"`
+	Truncated bool            `json:"truncated"`
+	LatencyMs float64         `json:"latency_ms"`
+	Error     string          `json:"error,omitempty"`
+}
+
+// validateReadOnlySQL rejects anything other than a single SELECT, EXPLAIN
+// SELECT or SHOW statement, and statements calling deniedFunctions. It is a
+// keyword check, not a parser: it rejects some harmless queries (a leading
+// comment, a WITH clause, a semicolon inside a string) and the query also
+// runs inside a READ ONLY transaction.
+func validateReadOnlySQL(query string) error {
+	query = strings.TrimSpace(query)
+	query = strings.TrimSuffix(query, ";")
+	if query == "" {
+		return fmt.Errorf("sql is required")
+	}
+	if strings.Contains(query, ";") {
+		return fmt.Errorf("only a single statement is allowed")
+	}
+	first := strings.Fields(query)[0]
+	keyword := strings.ToUpper(first)
+	if !readOnlyStatements[keyword] {
+		return fmt.Errorf("only SELECT, EXPLAIN and SHOW statements are allowed")
+	}
+	// EXPLAIN ANALYZE runs the statement it explains.
+	if keyword == "EXPLAIN" {
+		rest := strings.TrimSpace(query[len(first):])
+		if strings.HasPrefix(rest, "(") {
+			end := strings.Index(rest, ")")
+			if end < 0 {
+				return fmt.Errorf("unterminated EXPLAIN options")
+			}
+			rest = rest[end+1:]
+		}
+		words := strings.Fields(rest)
+		for len(words) > 0 && explainOptions[strings.ToUpper(words[0])] {
+			words = words[1:]
+		}
+		if len(words) == 0 || strings.ToUpper(words[0]) != "SELECT" {
+			return fmt.Errorf("EXPLAIN is only allowed for SELECT statements")
+		}
+	}
+	// Quoted identifiers name the same functions: "pg_cancel_backend"(1).
+	if name := deniedFunctions.FindString(strings.ReplaceAll(query, `"`, "")); name != "" {
+		return fmt.Errorf("%s is not allowed", strings.ToLower(name))
+	}
+	return nil
+}
+
+// runPgQuery executes query in a read-only transaction with a statement
+// timeout and returns at most maxQueryRows rows. The row limit is applied
+// here, on the client side: the query runs unmodified and the rows after
+// maxQueryRows are simply not read, so the server still does the work of a
+// query without its own LIMIT.
+func runPgQuery(ctx context.Context, dsn string, query string, timeout time.Duration) PgQueryResult {
+	result := PgQueryResult{Columns: []string{}, Rows: [][]interface{}{}}
+
+	dsn, _, err := withPostgresClientCert(dsn)
+	if err != nil {
+		result.Error = redactConnStrings(err.Error())
+		return result
+	}
+	db, err := postgresDB(ctx, dsn)
+	if err != nil {
+		result.Error = redactConnStrings(err.Error())
+		return result
+	}
+	defer trackClient("postgres", db.Close)()
+	db.SetMaxOpenConns(1)
+
+	start := time.Now()
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf("SET LOCAL statement_timeout = %d", timeout.Milliseconds())); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	rows, err := tx.QueryContext(ctx, strings.TrimSuffix(strings.TrimSpace(query), ";"))
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	defer rows.Close()
+
+	if result.Columns, err = rows.Columns(); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	for rows.Next() {
+		if len(result.Rows) == maxQueryRows {
+			result.Truncated = true
+			break
+		}
+		values := make([]interface{}, len(result.Columns))
+		pointers := make([]interface{}, len(values))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			result.Error = err.Error()
+			return result
+		}
+		for i, v := range values {
+			if b, ok := v.([]byte); ok {
+				values[i] = string(b)
+			}
+		}
+		result.Rows = append(result.Rows, values)
+	}
+	if err := rows.Err(); err != nil {
+		result.Error = err.Error()
+	}
+	result.RowCount = len(result.Rows)
+	result.LatencyMs = latencyMs(time.Since(start))
+	return result
+}
+
+// pgQueryHandler runs a read-only SQL statement against DATABASE_URL. It is
+// only available when ENABLE_QUERY=true and AUTH_TOKEN is set.
+func pgQueryHandler(w http.ResponseWriter, r *http.Request) {
+	if os.Getenv("ENABLE_QUERY") != "true" || os.Getenv("AUTH_TOKEN") == "" {
+		writeError(w, http.StatusForbidden, "query endpoint is disabled; set ENABLE_QUERY=true and AUTH_TOKEN to enable it")
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		writeError(w, http.StatusMethodNotAllowed, "use POST with a JSON body {\"sql\": \"...\"}")
+		return
+	}
+	dsn := os.Getenv("DATABASE_URL")
+	if dsn == "" || strings.HasPrefix(dsn, "mysql://") {
+		writeError(w, http.StatusServiceUnavailable, "DATABASE_URL is not set to a PostgreSQL connection string")
+		return
+	}
+
+	var req PgQueryRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxQueryBodyBytes)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+	if err := validateReadOnlySQL(req.SQL); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	timeout := checkTimeout(r.Context())
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+	result := runPgQuery(ctx, dsn, req.SQL, timeout)
+	status := http.StatusOK
+	if result.Error != "" {
+		status = http.StatusBadGateway
+	}
+	writeJSON(w, status, result)
+}
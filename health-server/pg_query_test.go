@@ -0,0 +1,43 @@
+package main
+
+import "testing"
+
+func TestValidateReadOnlySQL(t *testing.T) {
+	tests := []struct {
+		name    string
+		sql     string
+		wantErr bool
+	}{
+		{name: "select", sql: "SELECT 1"},
+		{name: "lowercase with trailing semicolon", sql: "  select now();  "},
+		{name: "show", sql: "SHOW server_version"},
+		{name: "explain select", sql: "EXPLAIN SELECT * FROM pg_stat_activity"},
+		{name: "explain analyze select", sql: "explain analyze verbose select 1"},
+		{name: "explain options select", sql: "EXPLAIN (ANALYZE, FORMAT JSON) SELECT 1"},
+		{name: "empty", sql: "  ; ", wantErr: true},
+		{name: "multiple statements", sql: "SELECT 1; DELETE FROM users", wantErr: true},
+		{name: "semicolon in trailing comment", sql: "SELECT 1 -- ; DROP TABLE users", wantErr: true},
+		{name: "leading line comment", sql: "-- read only\nDELETE FROM users", wantErr: true},
+		{name: "leading block comment", sql: "/* SELECT */ DELETE FROM users", wantErr: true},
+		{name: "delete", sql: "DELETE FROM users", wantErr: true},
+		{name: "with", sql: "WITH gone AS (DELETE FROM users RETURNING *) SELECT * FROM gone", wantErr: true},
+		{name: "explain analyze delete", sql: "EXPLAIN ANALYZE DELETE FROM users", wantErr: true},
+		{name: "explain options delete", sql: "EXPLAIN (ANALYZE) DELETE FROM users", wantErr: true},
+		{name: "explain unterminated options", sql: "EXPLAIN (ANALYZE SELECT 1", wantErr: true},
+		{name: "terminate backend", sql: "SELECT pg_terminate_backend(pid) FROM pg_stat_activity", wantErr: true},
+		{name: "cancel backend", sql: "SELECT pg_cancel_backend(12345)", wantErr: true},
+		{name: "quoted schema-qualified function", sql: `SELECT pg_catalog."PG_TERMINATE_BACKEND"(12345)`, wantErr: true},
+		{name: "reload conf", sql: "select PG_RELOAD_CONF()", wantErr: true},
+		{name: "read server file", sql: "SELECT pg_read_file('/etc/passwd')", wantErr: true},
+		{name: "dblink", sql: "SELECT dblink_exec('host=other', 'DROP TABLE users')", wantErr: true},
+		{name: "explain hides denied function", sql: "EXPLAIN ANALYZE SELECT pg_cancel_backend(1)", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateReadOnlySQL(tt.sql)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateReadOnlySQL(%q) error = %v, wantErr %v", tt.sql, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// resultCache holds the most recent dependency check outcomes.
+type resultCache struct {
+	mu        sync.RWMutex
+	results   map[string]checkOutcome
+	allOK     bool
+	populated bool
+}
+
+var checkCache = &resultCache{}
+
+func (c *resultCache) store(results map[string]checkOutcome, allOK bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.results = results
+	c.allOK = allOK
+	c.populated = true
+}
+
+// snapshot returns a copy of the cached outcomes, whether they all passed,
+// and whether a poll has completed yet.
+func (c *resultCache) snapshot() (map[string]checkOutcome, bool, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	results := make(map[string]checkOutcome, len(c.results))
+	for name, outcome := range c.results {
+		results[name] = outcome
+	}
+	return results, c.allOK, c.populated
+}
+
+// lookup returns the cached outcome for one dependency and whether a poll
+// has completed yet.
+func (c *resultCache) lookup(name string) (checkOutcome, bool, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	outcome, ok := c.results[name]
+	return outcome, ok, c.populated
+}
+
+// getPollInterval returns POLL_INTERVAL (default 30s); zero disables polling.
+func getPollInterval() time.Duration {
+	return getEnvDuration("POLL_INTERVAL", 30*time.Second)
+}
+
+// pollerEnabled reports whether the background poller is running.
+func pollerEnabled() bool {
+	return getPollInterval() > 0
+}
+
+// startPoller runs every configured check immediately and then once per
+// interval until ctx is cancelled, so endpoints can serve cached results
+// without opening new connections per request.
+func startPoller(ctx context.Context, interval time.Duration) {
+	poll := func() {
+		checkCtx, cancel := context.WithTimeout(ctx, checkTimeout(ctx))
+		defer cancel()
+		results, allOK := runAllChecks(checkCtx)
+		checkCache.store(results, allOK)
+		slog.Debug("dependency poll complete", "checks", len(results), "all_ok", allOK)
+	}
+
+	go func() {
+		poll()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				poll()
+			}
+		}
+	}()
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"time"
+)
+
+// startPprofServer serves net/http/pprof under /debug/pprof/ when
+// ENABLE_PPROF=true. It listens on its own port (PPROF_PORT, default 6060)
+// bound to PPROF_BIND_ADDR (default 127.0.0.1), so the profiles are reached
+// from a console session rather than exposed on the public route. It returns
+// nil when profiling is disabled.
+func startPprofServer() *http.Server {
+	if os.Getenv("ENABLE_PPROF") != "true" {
+		return nil
+	}
+	port := os.Getenv("PPROF_PORT")
+	if port == "" {
+		port = "6060"
+	}
+	bindAddr := os.Getenv("PPROF_BIND_ADDR")
+	if bindAddr == "" {
+		bindAddr = "127.0.0.1"
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	server := &http.Server{
+		Addr:              net.JoinHostPort(bindAddr, port),
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	go func() {
+		slog.Info("pprof server starting", "addr", server.Addr)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("pprof server failed", "error", err)
+		}
+	}()
+	return server
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"strings"
+)
+
+// defaultTrustedProxies are the ranges a load balancer in front of the app
+// connects from when TRUSTED_PROXY_CIDRS is unset: loopback and the private
+// address blocks.
+var defaultTrustedProxies = []string{
+	"127.0.0.0/8", "10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "100.64.0.0/10",
+	"::1/128", "fc00::/7",
+}
+
+// trustedProxies are the peers whose X-Forwarded-For header is believed.
+// It is empty unless TRUST_PROXY=true; set by configureTrustedProxies.
+var trustedProxies []netip.Prefix
+
+// configureTrustedProxies reads TRUST_PROXY and TRUSTED_PROXY_CIDRS.
+// Invalid CIDRs are logged and skipped.
+func configureTrustedProxies() {
+	if os.Getenv("TRUST_PROXY") != "true" {
+		return
+	}
+	cidrs := defaultTrustedProxies
+	if val := os.Getenv("TRUSTED_PROXY_CIDRS"); val != "" {
+		cidrs = strings.Split(val, ",")
+	}
+	for _, cidr := range cidrs {
+		cidr = strings.TrimSpace(cidr)
+		if cidr == "" {
+			continue
+		}
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			// Accept a bare address as a single-host range.
+			addr, addrErr := netip.ParseAddr(cidr)
+			if addrErr != nil {
+				slog.Warn("ignoring invalid TRUSTED_PROXY_CIDRS entry", "value", cidr, "error", err)
+				continue
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		trustedProxies = append(trustedProxies, prefix.Masked())
+	}
+}
+
+func isTrustedProxy(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, prefix := range trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP returns the address of the client behind r. The peer address is
+// used unless it is a trusted proxy, in which case X-Forwarded-For is walked
+// from the right and the first address that isn't itself a trusted proxy
+// wins, so a client can't spoof its IP by sending the header directly.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	peer, err := netip.ParseAddr(host)
+	if err != nil || !isTrustedProxy(peer) {
+		return host
+	}
+
+	var hops []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+	client := host
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			break
+		}
+		client = addr.Unmap().String()
+		if !isTrustedProxy(addr) {
+			break
+		}
+	}
+	return client
+}
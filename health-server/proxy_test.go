@@ -0,0 +1,55 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name       string
+		trustProxy string
+		cidrs      string
+		remoteAddr string
+		xff        []string
+		want       string
+	}{
+		{name: "trust proxy unset", cidrs: "10.0.0.0/8", remoteAddr: "10.0.0.5:4000", xff: []string{"203.0.113.9"}, want: "10.0.0.5"},
+		{name: "untrusted peer sending header", trustProxy: "true", cidrs: "10.0.0.0/8", remoteAddr: "198.51.100.1:4000", xff: []string{"203.0.113.9"}, want: "198.51.100.1"},
+		{name: "trusted peer", trustProxy: "true", cidrs: "10.0.0.0/8", remoteAddr: "10.0.0.5:4000", xff: []string{"203.0.113.9"}, want: "203.0.113.9"},
+		{name: "trusted peer without header", trustProxy: "true", cidrs: "10.0.0.0/8", remoteAddr: "10.0.0.5:4000", want: "10.0.0.5"},
+		{name: "spoofed leftmost entry", trustProxy: "true", cidrs: "10.0.0.0/8", remoteAddr: "10.0.0.5:4000", xff: []string{"1.1.1.1, 203.0.113.9"}, want: "203.0.113.9"},
+		{name: "chain of trusted proxies", trustProxy: "true", cidrs: "10.0.0.0/8", remoteAddr: "10.0.0.5:4000", xff: []string{"1.1.1.1, 203.0.113.9, 10.0.0.7, 10.0.0.6"}, want: "203.0.113.9"},
+		{name: "all hops trusted", trustProxy: "true", cidrs: "10.0.0.0/8", remoteAddr: "10.0.0.5:4000", xff: []string{"10.0.0.8, 10.0.0.7"}, want: "10.0.0.8"},
+		{name: "header split across lines", trustProxy: "true", cidrs: "10.0.0.0/8", remoteAddr: "10.0.0.5:4000", xff: []string{"1.1.1.1", "203.0.113.9, 10.0.0.7"}, want: "203.0.113.9"},
+		{name: "malformed rightmost entry", trustProxy: "true", cidrs: "10.0.0.0/8", remoteAddr: "10.0.0.5:4000", xff: []string{"203.0.113.9, not-an-ip"}, want: "10.0.0.5"},
+		{name: "malformed entry behind trusted hop", trustProxy: "true", cidrs: "10.0.0.0/8", remoteAddr: "10.0.0.5:4000", xff: []string{"203.0.113.9, garbage, 10.0.0.7"}, want: "10.0.0.7"},
+		{name: "malformed entry left of client", trustProxy: "true", cidrs: "10.0.0.0/8", remoteAddr: "10.0.0.5:4000", xff: []string{"garbage, 203.0.113.9"}, want: "203.0.113.9"},
+		{name: "empty entry", trustProxy: "true", cidrs: "10.0.0.0/8", remoteAddr: "10.0.0.5:4000", xff: []string{"203.0.113.9,,"}, want: "10.0.0.5"},
+		{name: "ipv6 client", trustProxy: "true", cidrs: "10.0.0.0/8", remoteAddr: "10.0.0.5:4000", xff: []string{"2001:db8::1"}, want: "2001:db8::1"},
+		{name: "ipv4-mapped peer", trustProxy: "true", cidrs: "10.0.0.0/8", remoteAddr: "[::ffff:10.0.0.5]:4000", xff: []string{"::ffff:203.0.113.9"}, want: "203.0.113.9"},
+		{name: "bare address as trusted proxy", trustProxy: "true", cidrs: "10.0.0.5", remoteAddr: "10.0.0.5:4000", xff: []string{"10.0.0.6"}, want: "10.0.0.6"},
+		{name: "invalid cidr skipped", trustProxy: "true", cidrs: "nonsense, 10.0.0.0/8", remoteAddr: "10.0.0.5:4000", xff: []string{"203.0.113.9"}, want: "203.0.113.9"},
+		{name: "default private ranges", trustProxy: "true", remoteAddr: "192.168.1.10:4000", xff: []string{"203.0.113.9"}, want: "203.0.113.9"},
+		{name: "remote addr without port", trustProxy: "true", cidrs: "10.0.0.0/8", remoteAddr: "198.51.100.1", xff: []string{"203.0.113.9"}, want: "198.51.100.1"},
+	}
+	saved := trustedProxies
+	t.Cleanup(func() { trustedProxies = saved })
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TRUST_PROXY", tt.trustProxy)
+			t.Setenv("TRUSTED_PROXY_CIDRS", tt.cidrs)
+			trustedProxies = nil
+			configureTrustedProxies()
+
+			r := httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			for _, value := range tt.xff {
+				r.Header.Add("X-Forwarded-For", value)
+			}
+			if got := clientIP(r); got != tt.want {
+				t.Errorf("clientIP() = %q, want %q (X-Forwarded-For %q from %s)", got, tt.want, tt.xff, tt.remoteAddr)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// rateLimiterIdleTTL is how long a client's bucket is kept after its last
+// request.
+const rateLimiterIdleTTL = 10 * time.Minute
+
+type clientLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// ipRateLimiter hands out one token bucket per client IP.
+type ipRateLimiter struct {
+	mu        sync.Mutex
+	clients   map[string]*clientLimiter
+	limit     rate.Limit
+	burst     int
+	lastSweep time.Time
+}
+
+func newIPRateLimiter(limit rate.Limit, burst int) *ipRateLimiter {
+	return &ipRateLimiter{
+		clients:   make(map[string]*clientLimiter),
+		limit:     limit,
+		burst:     burst,
+		lastSweep: time.Now(),
+	}
+}
+
+// get returns the bucket for ip, dropping buckets that have been idle for
+// rateLimiterIdleTTL so the map doesn't grow without bound.
+func (l *ipRateLimiter) get(ip string) *rate.Limiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(l.lastSweep) > time.Minute {
+		for key, client := range l.clients {
+			if now.Sub(client.lastSeen) > rateLimiterIdleTTL {
+				delete(l.clients, key)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	client, ok := l.clients[ip]
+	if !ok {
+		client = &clientLimiter{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.clients[ip] = client
+	}
+	client.lastSeen = now
+	return client.limiter
+}
+
+// getRateLimit reads RATE_LIMIT (requests per second per client IP) and
+// RATE_LIMIT_BURST (default: RATE_LIMIT rounded up, at least 1). A limit of 0
+// disables rate limiting.
+func getRateLimit() (rate.Limit, int) {
+	raw := os.Getenv("RATE_LIMIT")
+	if raw == "" {
+		return 0, 0
+	}
+	limit, err := strconv.ParseFloat(raw, 64)
+	if err != nil || limit < 0 {
+		slog.Warn("invalid RATE_LIMIT, rate limiting disabled", "value", raw)
+		return 0, 0
+	}
+	burst := int(math.Max(1, math.Ceil(limit)))
+	if rawBurst := os.Getenv("RATE_LIMIT_BURST"); rawBurst != "" {
+		if n, err := strconv.Atoi(rawBurst); err == nil && n > 0 {
+			burst = n
+		} else {
+			slog.Warn("invalid RATE_LIMIT_BURST, using default", "value", rawBurst, "default", burst)
+		}
+	}
+	return rate.Limit(limit), burst
+}
+
+// rateLimit throttles each client IP to RATE_LIMIT requests per second,
+// answering 429 with Retry-After once its bucket is empty. The health probes
+// are exempt so the platform never sees them throttled. It is a no-op when
+// RATE_LIMIT is unset or 0.
+func rateLimit(next http.Handler) http.Handler {
+	limit, burst := getRateLimit()
+	if limit == 0 {
+		return next
+	}
+	limiter := newIPRateLimiter(limit, burst)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if unauthenticatedPaths[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+		reservation := limiter.get(clientIP(r)).Reserve()
+		if delay := reservation.Delay(); delay > 0 {
+			reservation.Cancel()
+			w.Header().Set("Retry-After", fmt.Sprint(int(math.Ceil(delay.Seconds()))))
+			writeError(w, http.StatusTooManyRequests, "rate limit exceeded")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// startupComplete is set once main() has finished runtime detection and any
+// WAIT_FOR dependency wait.
+var startupComplete atomic.Bool
+
+type ReadyCheck struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+type ReadyResponse struct {
+	Status    string                `json:"status"`
+	Timestamp string                `json:"timestamp"`
+	Checks    map[string]ReadyCheck `json:"checks"`
+	Failed    []string              `json:"failed,omitempty"`
+}
+
+// getReadinessChecks returns the database checks /ready runs. By default
+// every configured check is included; READINESS_CHECKS narrows that to an
+// explicit comma-separated list ("none" disables them all).
+func getReadinessChecks() []dbCheck {
+	val := strings.TrimSpace(os.Getenv("READINESS_CHECKS"))
+	if val == "" {
+		var checks []dbCheck
+		for _, check := range dbChecks {
+			if check.Configured() {
+				checks = append(checks, check)
+			}
+		}
+		return checks
+	}
+
+	wanted := make(map[string]bool)
+	for _, name := range strings.Split(val, ",") {
+		wanted[strings.ToLower(strings.TrimSpace(name))] = true
+	}
+	var checks []dbCheck
+	for _, check := range dbChecks {
+		if wanted[check.Name] {
+			checks = append(checks, check)
+		}
+	}
+	return checks
+}
+
+// readinessResult reports a check's status from the poller cache, or runs
+// it directly when background polling is disabled, so /ready verifies the
+// same thing (authentication and TLS included) either way.
+func readinessResult(ctx context.Context, check dbCheck) ReadyCheck {
+	if !check.Configured() {
+		return ReadyCheck{Error: check.EnvVar + " is not set"}
+	}
+	if !pollerEnabled() {
+		ctx, cancel := context.WithTimeout(ctx, checkTimeout(ctx))
+		defer cancel()
+		result, ok := check.Run(ctx)
+		if !ok {
+			return ReadyCheck{Error: checkResultError(result)}
+		}
+		return ReadyCheck{OK: true}
+	}
+	outcome, ok, populated := checkCache.lookup(check.Name)
+	if !populated {
+		return ReadyCheck{Error: "awaiting first dependency poll"}
+	}
+	if !ok {
+		return ReadyCheck{Error: "awaiting next dependency poll"}
+	}
+	return ReadyCheck{OK: outcome.OK, Error: outcome.Error}
+}
+
+func readyHandler(w http.ResponseWriter, r *http.Request) {
+	response := ReadyResponse{
+		Status:    "ready",
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		Checks:    make(map[string]ReadyCheck),
+	}
+
+	if !startupComplete.Load() {
+		response.Checks["startup"] = ReadyCheck{Error: getStartupPhase()}
+		response.Failed = append(response.Failed, "startup")
+	} else {
+		response.Checks["startup"] = ReadyCheck{OK: true}
+	}
+	if draining, since, reason := drain.status(); draining {
+		response.Checks["drain"] = ReadyCheck{Error: fmt.Sprintf("%s (since %s)", reason, since.Format(time.RFC3339))}
+		response.Failed = append(response.Failed, "drain")
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, check := range getReadinessChecks() {
+		wg.Add(1)
+		go func(check dbCheck) {
+			defer wg.Done()
+			result := readinessResult(r.Context(), check)
+			mu.Lock()
+			response.Checks[check.Name] = result
+			if !result.OK {
+				response.Failed = append(response.Failed, check.Name)
+			}
+			mu.Unlock()
+		}(check)
+	}
+	wg.Wait()
+	sort.Strings(response.Failed)
+
+	status := http.StatusOK
+	if len(response.Failed) > 0 {
+		response.Status = "not ready"
+		status = http.StatusServiceUnavailable
+	}
+	if draining, _, _ := drain.status(); draining {
+		response.Status = "draining"
+	}
+	writeJSON(w, status, response)
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+)
+
+// negotiateFormat picks json, yaml or text from ?format= or the Accept
+// header. JSON is the default.
+func negotiateFormat(r *http.Request) string {
+	switch format := strings.ToLower(r.URL.Query().Get("format")); format {
+	case "json", "yaml", "text":
+		return format
+	case "yml":
+		return "yaml"
+	case "txt":
+		return "text"
+	}
+
+	accept := r.Header.Get("Accept")
+	switch {
+	case strings.Contains(accept, "application/json"):
+		return "json"
+	case strings.Contains(accept, "yaml"):
+		return "yaml"
+	case strings.Contains(accept, "text/plain"):
+		return "text"
+	}
+	return "json"
+}
+
+// toYAMLNode converts v to a YAML node via its JSON encoding, so the output
+// keeps the JSON field names and ordering.
+func toYAMLNode(v interface{}) (*yaml.Node, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	resetYAMLStyle(&doc)
+	if len(doc.Content) == 0 {
+		return &doc, nil
+	}
+	return doc.Content[0], nil
+}
+
+// resetYAMLStyle switches JSON's flow style back to block style.
+func resetYAMLStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		resetYAMLStyle(child)
+	}
+}
+
+// writeText renders a mapping node as an aligned plain-text table, with
+// nested mappings shown as indented sections.
+func writeText(out io.Writer, node *yaml.Node) {
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	writeTextMapping(tw, node, "")
+	tw.Flush()
+}
+
+func writeTextMapping(tw *tabwriter.Writer, node *yaml.Node, indent string) {
+	if node.Kind != yaml.MappingNode {
+		fmt.Fprintf(tw, "%s%s\n", indent, textValue(node))
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, val := node.Content[i].Value, node.Content[i+1]
+		if val.Kind == yaml.MappingNode {
+			fmt.Fprintf(tw, "%s%s:\n", indent, key)
+			writeTextMapping(tw, val, indent+"  ")
+			continue
+		}
+		fmt.Fprintf(tw, "%s%s\t%s\n", indent, key, textValue(val))
+	}
+}
+
+func textValue(node *yaml.Node) string {
+	if node.Kind == yaml.SequenceNode {
+		items := make([]string, 0, len(node.Content))
+		for _, item := range node.Content {
+			items = append(items, textValue(item))
+		}
+		return strings.Join(items, ", ")
+	}
+	return node.Value
+}
+
+// prettyWriter marks a response whose JSON should be indented.
+type prettyWriter struct {
+	http.ResponseWriter
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (p prettyWriter) Unwrap() http.ResponseWriter {
+	return p.ResponseWriter
+}
+
+// prettyJSON switches writeJSON to indented output for requests with
+// ?pretty=true, or for every request when PRETTY_JSON=true (?pretty=false
+// still opts out). Compact JSON stays the default for machine consumers.
+func prettyJSON(next http.Handler) http.Handler {
+	byDefault := os.Getenv("PRETTY_JSON") == "true"
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pretty := byDefault
+		if val := r.URL.Query().Get("pretty"); val != "" {
+			pretty = val == "true" || val == "1"
+		}
+		if pretty {
+			w = prettyWriter{w}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// wantsPretty reports whether prettyJSON marked w, looking through any
+// writers wrapped around it.
+func wantsPretty(w http.ResponseWriter) bool {
+	for {
+		switch rw := w.(type) {
+		case prettyWriter:
+			return true
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = rw.Unwrap()
+		default:
+			return false
+		}
+	}
+}
+
+// ErrorResponse is the envelope every handler uses to report an error.
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// writeJSON sends v as a JSON response with the given status.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	if wantsPretty(w) {
+		enc.SetIndent("", "  ")
+	}
+	enc.Encode(v)
+}
+
+// writeError sends msg in the standard ErrorResponse envelope.
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, ErrorResponse{Error: msg})
+}
+
+// writeNegotiated encodes v in the format the client asked for.
+func writeNegotiated(w http.ResponseWriter, r *http.Request, status int, v interface{}) {
+	format := negotiateFormat(r)
+	if format == "json" {
+		if r.Method == http.MethodHead {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(status)
+			return
+		}
+		writeJSON(w, status, v)
+		return
+	}
+
+	node, err := toYAMLNode(v)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	var buf bytes.Buffer
+	if format == "yaml" {
+		w.Header().Set("Content-Type", "application/yaml")
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		enc.Encode(node)
+		enc.Close()
+	} else {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		writeText(&buf, node)
+	}
+	w.WriteHeader(status)
+	if r.Method != http.MethodHead {
+		w.Write(buf.Bytes())
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Route is one HTTP endpoint served by the health server.
+type Route struct {
+	Path        string `json:"path"`
+	Description string `json:"description"`
+	handler     http.Handler
+}
+
+// routes is the registry every endpoint is served from. The info page, the
+// startup banner and /routes are all generated from it, so a handler only
+// needs to be added here.
+var routes []Route
+
+// buildRoutes returns the full route table. healthPaths are the paths the
+// liveness check is served on (see configureHealthPaths); it fails when one
+// isn't a plain path or is already taken by another endpoint, rather than
+// letting the mux panic at registration.
+func buildRoutes(healthPaths []string) ([]Route, error) {
+	table := []Route{
+		{Path: "/", Description: "This info page", handler: http.HandlerFunc(infoHandler)},
+		{Path: "/ready", Description: "Readiness check (verifies configured dependencies)", handler: http.HandlerFunc(readyHandler)},
+		{Path: "/routes", Description: "Every registered endpoint with a short description", handler: http.HandlerFunc(routesHandler)},
+		{Path: "/check/postgres", Description: "PostgreSQL connectivity check (DATABASE_URL)", handler: http.HandlerFunc(postgresCheckHandler)},
+		{Path: "/check/postgres/pool", Description: "PostgreSQL connection usage vs max_connections", handler: http.HandlerFunc(postgresPoolHandler)},
+		{Path: "/check/postgres/locks", Description: "PostgreSQL queries blocked on locks and the PIDs blocking them (?limit=50&redact=true)", handler: http.HandlerFunc(postgresLocksHandler)},
+		{Path: "/check/postgres/ssl", Description: "PostgreSQL TLS version, cipher and certificate verification (PGSSLROOTCERT)", handler: http.HandlerFunc(postgresSSLHandler)},
+		{Path: "/check/dns-over-db", Description: "Whether the DATABASE_URL host resolves to a private (VPC) address", handler: http.HandlerFunc(dbResolutionHandler)},
+		{Path: "/check/redis", Description: "Redis/Valkey PING check (REDIS_URL)", handler: http.HandlerFunc(redisCheckHandler)},
+		{Path: "/check/redis/info", Description: "Redis/Valkey INFO: memory, clients, evictions and role", handler: http.HandlerFunc(redisInfoHandler)},
+		{Path: "/check/redis/cluster", Description: "Redis cluster state, slot coverage, masters/replicas and failed nodes", handler: http.HandlerFunc(redisClusterHandler)},
+		{Path: "/check/redis/latency", Description: "Redis/Valkey latency spikes from LATENCY LATEST and LATENCY HISTORY, with their causes", handler: http.HandlerFunc(redisLatencyHandler)},
+		{Path: "/check/mysql", Description: "MySQL connectivity check (MYSQL_URL)", handler: http.HandlerFunc(mysqlCheckHandler)},
+		{Path: "/check/mysql/status", Description: "MySQL replication status, process list and connection usage vs max_connections", handler: http.HandlerFunc(mysqlStatusHandler)},
+		{Path: "/check/mongodb", Description: "MongoDB ping and topology check (MONGODB_URI)", handler: http.HandlerFunc(mongodbCheckHandler)},
+		{Path: "/check/mongodb/rs", Description: "MongoDB replica set members, primary and replication lag", handler: http.HandlerFunc(mongodbReplicaSetHandler)},
+		{Path: "/check/kafka", Description: "Kafka broker reachability, topic partitions and consumer lag (KAFKA_BROKERS)", handler: http.HandlerFunc(kafkaCheckHandler)},
+		{Path: "/check/kafka/roundtrip", Description: "Produce a test record to KAFKA_TEST_TOPIC and consume it back (ENABLE_KAFKA_ROUNDTRIP=true required)", handler: http.HandlerFunc(kafkaRoundTripHandler)},
+		{Path: "/check/opensearch", Description: "OpenSearch cluster health (OPENSEARCH_URL)", handler: http.HandlerFunc(opensearchCheckHandler)},
+		{Path: "/check/opensearch/indices", Description: "OpenSearch indices with doc counts and sizes, plus a match_all count on OPENSEARCH_TEST_INDEX", handler: http.HandlerFunc(opensearchIndicesHandler)},
+		{Path: "/check/tcp-batch", Description: "POST a JSON array of {host, port} to dial them all concurrently", handler: http.HandlerFunc(tcpBatchHandler)},
+		{Path: "/check/http-proxy", Description: "Effective HTTP(S)_PROXY/NO_PROXY settings and a test request through them (?url=)", handler: http.HandlerFunc(httpProxyHandler)},
+		{Path: "/check/all", Description: "Cached results of every configured database check (?live=true to re-run)", handler: http.HandlerFunc(allChecksHandler)},
+		{Path: "/check/auto", Description: "Check every *_URL, *_URI and *_BROKERS env var by its scheme", handler: http.HandlerFunc(autoCheckHandler)},
+		{Path: "/benchmark", Description: "Latency distribution of N sequential checks against one dependency (?target=postgres&n=20)", handler: http.HandlerFunc(benchmarkHandler)},
+		{Path: "/pg-query", Description: "POST a read-only SQL statement to run against DATABASE_URL (ENABLE_QUERY=true and AUTH_TOKEN required)", handler: http.HandlerFunc(pgQueryHandler)},
+		{Path: "/file", Description: "Contents of a file under VIEWABLE_DIRS (?path=/app/config.yaml; AUTH_TOKEN required)", handler: http.HandlerFunc(fileHandler)},
+		{Path: "/ls", Description: "Files in a directory under VIEWABLE_DIRS with size, mode and mtime (?dir=/app/scripts; AUTH_TOKEN required)", handler: http.HandlerFunc(lsHandler)},
+		{Path: "/logs", Description: "Recent server log lines (?n=50&level=error&format=text)", handler: http.HandlerFunc(logsHandler)},
+		{Path: "/logs/stream", Description: "WebSocket stream of new log lines (?backlog=50&level=warn)", handler: http.HandlerFunc(logStreamHandler)},
+		{Path: "/self-test", Description: "Call every endpoint in-process and report which ones respond", handler: http.HandlerFunc(selfTestHandler)},
+		{Path: "/openapi.json", Description: "OpenAPI 3 description of every endpoint", handler: http.HandlerFunc(openAPIHandler)},
+		{Path: "/stats", Description: "Request counts per endpoint and uptime", handler: http.HandlerFunc(statsHandler)},
+		{Path: "/metrics", Description: "Prometheus metrics", handler: metricsHandler()},
+		{Path: "/env", Description: "Environment variables with secrets redacted (?prefix= to filter)", handler: http.HandlerFunc(envHandler)},
+		{Path: "/env/diff", Description: "Expected env vars (EXPECTED_ENV) that are missing, empty or unsubstituted", handler: http.HandlerFunc(envDiffHandler)},
+		{Path: "/diagnose.json", Description: "Runtime, configured dependencies and their check results, memory, disk and env presence as one JSON object", handler: http.HandlerFunc(diagnoseHandler)},
+		{Path: "/sysinfo", Description: "Go runtime, cgroup limits, and disk usage", handler: http.HandlerFunc(sysinfoHandler)},
+		{Path: "/dns", Description: "Resolve a hostname (?host=&type=txt|mx|srv)", handler: http.HandlerFunc(dnsHandler)},
+		{Path: "/tcp", Description: "TCP connectivity check (?host=&port=&timeout=)", handler: http.HandlerFunc(tcpHandler)},
+		{Path: "/trace", Description: "Traceroute-style hop analysis (?host=&port=&max_hops=&method=tcp)", handler: http.HandlerFunc(traceHandler)},
+		{Path: "/http", Description: "Outbound HTTP GET with timing breakdown (?url=)", handler: http.HandlerFunc(httpProbeHandler)},
+		{Path: "/tls", Description: "TLS certificate chain inspection (?host=&port=&insecure=true)", handler: http.HandlerFunc(tlsInspectHandler)},
+		{Path: "/clock", Description: "System clock offset from NTP (NTP_SERVER)", handler: http.HandlerFunc(clockHandler)},
+		{Path: "/egress", Description: "Public IP outbound requests come from (add it to database trusted sources)", handler: http.HandlerFunc(egressHandler)},
+		{Path: "/whoami", Description: "App, component, region and instance size this container runs as", handler: http.HandlerFunc(whoamiHandler)},
+		{Path: "/version", Description: "Build version, commit, and date", handler: http.HandlerFunc(versionHandler)},
+		{Path: "/exec", Description: "Run a diagnostic script (?script=diagnose|test-db|test-connectivity&arg=)", handler: http.HandlerFunc(execHandler)},
+		{Path: "/admin/drain", Description: "POST to fail /ready (taking the container out of rotation) while /health keeps passing (AUTH_TOKEN required)", handler: http.HandlerFunc(drainHandler)},
+		{Path: "/admin/undrain", Description: "POST to end drain mode", handler: http.HandlerFunc(undrainHandler)},
+		{Path: "/unhealthy", Description: "POST to force the health check to fail, DELETE to restore (AUTH_TOKEN required)", handler: http.HandlerFunc(unhealthyHandler)},
+	}
+
+	for i, route := range table {
+		switch {
+		case strings.HasPrefix(route.Path, "/check/"):
+			table[i].handler = withCheckTimeout(withIPVersion(route.handler))
+		case route.Path == "/tcp" || route.Path == "/dns":
+			table[i].handler = withIPVersion(route.handler)
+		}
+	}
+	for _, healthPath := range healthPaths {
+		if strings.ContainsAny(healthPath, " \t{}") || strings.TrimSuffix(path.Clean(healthPath), "/") != strings.TrimSuffix(healthPath, "/") {
+			return nil, fmt.Errorf("HEALTH_PATH %q is not a plain URL path such as /health", healthPath)
+		}
+		for _, route := range table {
+			if route.Path == healthPath {
+				return nil, fmt.Errorf("HEALTH_PATH %q collides with an existing endpoint; pick an unused path", healthPath)
+			}
+		}
+		table = append(table, Route{Path: healthPath, Description: healthRouteDescription, handler: http.HandlerFunc(healthHandler)})
+	}
+	return table, nil
+}
+
+// registerRoutes serves every route in the registry on mux.
+func registerRoutes(mux *http.ServeMux) {
+	for _, route := range routes {
+		mux.Handle(route.Path, route.handler)
+	}
+}
+
+// sortedRoutes returns the registry ordered by path.
+func sortedRoutes() []Route {
+	sorted := append([]Route(nil), routes...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Path < sorted[j].Path })
+	return sorted
+}
+
+// routeDescriptions maps each registered path to its description for the
+// info page.
+func routeDescriptions() map[string]string {
+	descriptions := make(map[string]string, len(routes))
+	for _, route := range routes {
+		descriptions[route.Path] = route.Description
+	}
+	return descriptions
+}
+
+func routesHandler(w http.ResponseWriter, r *http.Request) {
+	writeNegotiated(w, r, http.StatusOK, sortedRoutes())
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// runtimeVersionTimeout bounds each "<runtime> --version" invocation.
+const runtimeVersionTimeout = 5 * time.Second
+
+var versionPattern = regexp.MustCompile(`\d+\.\d+(\.\d+)?`)
+
+// runtimeCandidates lists the runtimes auto-detection looks for, in priority
+// order: when several are on PATH the first match becomes the reported
+// runtime. DEBUG_RUNTIME overrides detection entirely.
+var runtimeCandidates = []struct {
+	Name        string
+	Binary      string
+	VersionArgs []string
+}{
+	{Name: "node", Binary: "node", VersionArgs: []string{"--version"}},
+	{Name: "python", Binary: "python3", VersionArgs: []string{"--version"}},
+	{Name: "java", Binary: "java", VersionArgs: []string{"-version"}},
+	{Name: "ruby", Binary: "ruby", VersionArgs: []string{"--version"}},
+	{Name: "php", Binary: "php", VersionArgs: []string{"--version"}},
+	{Name: "dotnet", Binary: "dotnet", VersionArgs: []string{"--version"}},
+	{Name: "go", Binary: "go", VersionArgs: []string{"version"}},
+	{Name: "deno", Binary: "deno", VersionArgs: []string{"--version"}},
+	{Name: "bun", Binary: "bun", VersionArgs: []string{"--version"}},
+}
+
+// runtimeInfo is the cached result of runtime detection.
+type runtimeInfo struct {
+	Name     string
+	Version  string
+	Detected []string
+}
+
+// cachedRuntime holds the result of detectRuntime so handlers don't search
+// PATH on every probe.
+var cachedRuntime atomic.Pointer[runtimeInfo]
+
+func detectRuntime() *runtimeInfo {
+	info := &runtimeInfo{Name: "unknown", Detected: []string{}}
+	for _, candidate := range runtimeCandidates {
+		if _, err := exec.LookPath(candidate.Binary); err == nil {
+			info.Detected = append(info.Detected, candidate.Name)
+		}
+	}
+	if len(info.Detected) > 0 {
+		info.Name = info.Detected[0]
+	}
+	if val := os.Getenv("DEBUG_RUNTIME"); val != "" {
+		info.Name = val
+	}
+	info.Version = runtimeVersion(info.Name)
+	return info
+}
+
+// runtimeVersion runs the runtime's version command and extracts the
+// version number, returning "unknown" when that isn't possible.
+func runtimeVersion(name string) string {
+	for _, candidate := range runtimeCandidates {
+		if candidate.Name != name {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), runtimeVersionTimeout)
+		defer cancel()
+		// java -version writes to stderr, so capture both streams.
+		out, err := exec.CommandContext(ctx, candidate.Binary, candidate.VersionArgs...).CombinedOutput()
+		if err != nil {
+			return "unknown"
+		}
+		if version := versionPattern.FindString(string(out)); version != "" {
+			return version
+		}
+		if line, _, _ := strings.Cut(strings.TrimSpace(string(out)), "\n"); line != "" {
+			return line
+		}
+	}
+	return "unknown"
+}
+
+// refreshRuntimeType re-runs detection and updates the cached value.
+func refreshRuntimeType() string {
+	info := detectRuntime()
+	cachedRuntime.Store(info)
+	setContainerInfo(info.Name, getContainerType())
+	return info.Name
+}
+
+func getRuntimeInfo() *runtimeInfo {
+	if info := cachedRuntime.Load(); info != nil {
+		return info
+	}
+	refreshRuntimeType()
+	return cachedRuntime.Load()
+}
+
+func getRuntimeType() string {
+	return getRuntimeInfo().Name
+}
+
+func getRuntimeVersion() string {
+	return getRuntimeInfo().Version
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// defaultScripts is advertised when the scripts directory can't be read, minus
+// any that are missing from this image.
+var defaultScripts = map[string]string{
+	"/app/scripts/diagnose.sh":          "Full system diagnostic report",
+	"/app/scripts/test-db.sh":           "Database connectivity test (postgres|mysql|redis|mongodb|kafka|opensearch)",
+	"/app/scripts/test-connectivity.sh": "Network connectivity test",
+}
+
+// advertisedScripts is populated by loadScripts at startup.
+var advertisedScripts = defaultScripts
+
+func getScriptsDir() string {
+	if val := os.Getenv("SCRIPTS_DIR"); val != "" {
+		return val
+	}
+	return "/app/scripts"
+}
+
+// loadScripts lists the executable *.sh files in the scripts directory,
+// describing each with its "# Description:" header comment.
+func loadScripts() map[string]string {
+	dir := getScriptsDir()
+	paths, err := filepath.Glob(filepath.Join(dir, "*.sh"))
+	if err != nil || len(paths) == 0 {
+		slog.Warn("no diagnostic scripts found", "dir", dir)
+		return availableScripts(defaultScripts)
+	}
+
+	scripts := make(map[string]string)
+	for _, path := range paths {
+		if checkExecutable(path) != nil {
+			continue
+		}
+		scripts[path] = scriptDescription(path)
+	}
+	if len(scripts) == 0 {
+		return availableScripts(defaultScripts)
+	}
+	return scripts
+}
+
+// availableScripts drops scripts that are missing or not executable, logging
+// a warning for each so users aren't pointed at paths that don't exist.
+func availableScripts(scripts map[string]string) map[string]string {
+	available := make(map[string]string)
+	for path, desc := range scripts {
+		if err := checkExecutable(path); err != nil {
+			slog.Warn("advertised script unavailable", "path", path, "error", err)
+			continue
+		}
+		available[path] = desc
+	}
+	return available
+}
+
+// checkExecutable reports why path can't be run as a script, if it can't.
+func checkExecutable(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return fmt.Errorf("%s is a directory", path)
+	}
+	if info.Mode()&0111 == 0 {
+		return fmt.Errorf("%s is not executable", path)
+	}
+	return nil
+}
+
+// scriptDescription reads the "# Description:" line from a script's leading
+// comment block.
+func scriptDescription(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "#") {
+			break
+		}
+		if desc, ok := strings.CutPrefix(line, "# Description:"); ok {
+			return strings.TrimSpace(desc)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+)
+
+// selfTestSkipped are routes /self-test doesn't call: itself, and handlers
+// that run scripts, hold the connection open, or take tens of seconds.
+var selfTestSkipped = map[string]string{
+	"/self-test":   "recursive",
+	"/exec":        "runs a diagnostic script",
+	"/logs/stream": "WebSocket stream",
+	"/trace":       "slow network probe",
+}
+
+type SelfTestResult struct {
+	Path      string  `json:"path"`
+	Status    int     `json:"status,omitempty"`
+	OK        bool    `json:"ok"`
+	LatencyMs float64 `json:"latency_ms"`
+	Skipped   string  `json:"skipped,omitempty"`
+	Error     string  `json:"error,omitempty"`
+}
+
+type SelfTestResponse struct {
+	OK      bool             `json:"ok"`
+	Passed  int              `json:"passed"`
+	Failed  int              `json:"failed"`
+	Skipped int              `json:"skipped"`
+	TotalMs float64          `json:"total_ms"`
+	Results []SelfTestResult `json:"results"`
+}
+
+// selfTestRoute calls route's handler with a plain GET and reports whether
+// it answered without panicking or returning a 5xx other than 503 (which
+// the check endpoints use for an unreachable dependency).
+func selfTestRoute(ctx context.Context, route Route) SelfTestResult {
+	result := SelfTestResult{Path: route.Path}
+	req := httptest.NewRequest(http.MethodGet, route.Path, nil).WithContext(ctx)
+	rec := httptest.NewRecorder()
+
+	start := time.Now()
+	done := make(chan string, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				done <- fmt.Sprintf("handler panicked: %v", p)
+			}
+		}()
+		route.handler.ServeHTTP(rec, req)
+		done <- ""
+	}()
+
+	select {
+	case panicMsg := <-done:
+		result.LatencyMs = latencyMs(time.Since(start))
+		if panicMsg != "" {
+			result.Error = panicMsg
+			return result
+		}
+	case <-ctx.Done():
+		// The handler is ignoring its context; leave it running and stop
+		// looking at its recorder.
+		result.LatencyMs = latencyMs(time.Since(start))
+		result.Error = "handler did not return before the timeout"
+		return result
+	}
+
+	result.Status = rec.Code
+	result.OK = rec.Code < 500 || rec.Code == http.StatusServiceUnavailable
+	if !result.OK {
+		result.Error = fmt.Sprintf("unexpected status %d", rec.Code)
+	} else if rec.Body.Len() == 0 {
+		result.OK = false
+		result.Error = "empty response body"
+	}
+	return result
+}
+
+// selfTestHandler invokes every registered handler in-process and reports
+// which ones responded, as a smoke test of a fresh deploy.
+func selfTestHandler(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	sorted := sortedRoutes()
+	response := SelfTestResponse{Results: make([]SelfTestResult, len(sorted))}
+
+	var wg sync.WaitGroup
+	for i, route := range sorted {
+		if reason, skip := selfTestSkipped[route.Path]; skip {
+			response.Results[i] = SelfTestResult{Path: route.Path, OK: true, Skipped: reason}
+			continue
+		}
+		wg.Add(1)
+		go func(i int, route Route) {
+			defer wg.Done()
+			// Leave headroom over CHECK_TIMEOUT so check endpoints can
+			// report their own timeouts.
+			ctx, cancel := context.WithTimeout(r.Context(), checkTimeout(r.Context())+2*time.Second)
+			defer cancel()
+			response.Results[i] = selfTestRoute(ctx, route)
+		}(i, route)
+	}
+	wg.Wait()
+
+	for _, result := range response.Results {
+		switch {
+		case result.Skipped != "":
+			response.Skipped++
+		case result.OK:
+			response.Passed++
+		default:
+			response.Failed++
+		}
+	}
+	response.OK = response.Failed == 0
+	response.TotalMs = latencyMs(time.Since(start))
+	writeCheckResult(w, response.OK, response)
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/net/http/httpproxy"
+	"golang.org/x/net/proxy"
+)
+
+// socksProxySetting returns the SOCKS5 proxy outbound checks dial through:
+// SOCKS_PROXY, or ALL_PROXY (all_proxy) when it names a socks5:// or
+// socks5h:// URL. An ALL_PROXY with another scheme is left to the HTTP
+// clients and ignored here.
+func socksProxySetting() string {
+	if val := os.Getenv("SOCKS_PROXY"); val != "" {
+		return val
+	}
+	val := os.Getenv("ALL_PROXY")
+	if val == "" {
+		val = os.Getenv("all_proxy")
+	}
+	lower := strings.ToLower(val)
+	if strings.HasPrefix(lower, "socks5://") || strings.HasPrefix(lower, "socks5h://") {
+		return val
+	}
+	return ""
+}
+
+// socksProxyConfigured reports whether outbound checks dial through a SOCKS5
+// proxy.
+func socksProxyConfigured() bool {
+	return socksProxySetting() != ""
+}
+
+// socksProxyURL parses the SOCKS5 proxy setting; a bare host:port is taken
+// as socks5://host:port.
+func socksProxyURL() (*url.URL, error) {
+	raw := socksProxySetting()
+	if !strings.Contains(raw, "://") {
+		raw = "socks5://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SOCKS proxy setting: %s", redactConnStrings(err.Error()))
+	}
+	if u.Scheme != "socks5" && u.Scheme != "socks5h" {
+		return nil, fmt.Errorf("invalid SOCKS proxy setting: scheme must be socks5 or socks5h, got %q", u.Scheme)
+	}
+	if u.Port() == "" {
+		u.Host = net.JoinHostPort(u.Hostname(), "1080")
+	}
+	return u, nil
+}
+
+// bypassesSOCKS reports whether addr (host:port) is dialed directly despite
+// the SOCKS5 proxy: localhost, loopback addresses and hosts matching
+// NO_PROXY, with the same rules the HTTP clients apply.
+func bypassesSOCKS(addr string) bool {
+	noProxy := os.Getenv("NO_PROXY")
+	if noProxy == "" {
+		noProxy = os.Getenv("no_proxy")
+	}
+	cfg := httpproxy.Config{HTTPSProxy: "socks5://proxy", NoProxy: noProxy}
+	proxyURL, err := cfg.ProxyFunc()(&url.URL{Scheme: "https", Host: addr})
+	return err == nil && proxyURL == nil
+}
+
+// outboundDialer returns the dialer outbound checks use for addr: direct, or
+// through the SOCKS5 proxy. The environment is read on every call, like
+// checkHTTPProxy does.
+func outboundDialer(addr string) (proxy.ContextDialer, error) {
+	direct := &net.Dialer{KeepAlive: 30 * time.Second}
+	if !socksProxyConfigured() || bypassesSOCKS(addr) {
+		return direct, nil
+	}
+	u, err := socksProxyURL()
+	if err != nil {
+		return nil, err
+	}
+	socks, err := proxy.FromURL(u, direct)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SOCKS proxy setting: %s", redactConnStrings(err.Error()))
+	}
+	return socks.(proxy.ContextDialer), nil
+}
+
+// dialOutbound connects to addr directly or through the SOCKS5 proxy, over
+// the IP version ctx asks for, if any. Through the proxy that only applies
+// to reaching the proxy, since it resolves the target itself. It matches the
+// dial hooks of net/http and the database drivers, so a bad proxy setting
+// surfaces as the error of whichever check dialed.
+func dialOutbound(ctx context.Context, network, addr string) (net.Conn, error) {
+	network = dialNetwork(ctx, network)
+	dialer, err := outboundDialer(addr)
+	if err != nil {
+		return nil, err
+	}
+	return dialer.DialContext(ctx, network, addr)
+}
+
+// useCheckDialer reports whether database clients must dial with
+// dialOutbound rather than their driver's own dialer: a SOCKS5 proxy is
+// configured or ctx asks for one IP version.
+func useCheckDialer(ctx context.Context) bool {
+	return socksProxyConfigured() || ipVersion(ctx) != ""
+}
+
+// checkDialer adapts dialOutbound to the dialer interfaces of lib/pq and
+// the MongoDB driver. The MongoDB driver dials without the check's context,
+// so the IP version travels with the dialer instead.
+type checkDialer struct {
+	ipVersion string
+}
+
+func (d checkDialer) withIPVersion(ctx context.Context) context.Context {
+	if d.ipVersion == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, ipVersionKey{}, d.ipVersion)
+}
+
+func (d checkDialer) Dial(network, addr string) (net.Conn, error) {
+	return dialOutbound(d.withIPVersion(context.Background()), network, addr)
+}
+
+func (d checkDialer) DialTimeout(network, addr string, timeout time.Duration) (net.Conn, error) {
+	ctx, cancel := context.WithTimeout(d.withIPVersion(context.Background()), timeout)
+	defer cancel()
+	return dialOutbound(ctx, network, addr)
+}
+
+func (d checkDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	return dialOutbound(d.withIPVersion(ctx), network, addr)
+}
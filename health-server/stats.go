@@ -0,0 +1,50 @@
+package main
+
+import (
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// startTime is when the process started serving; set at the top of main().
+var startTime = time.Now()
+
+// requestCounts maps a route pattern to an *atomic.Int64 hit counter.
+var requestCounts sync.Map
+
+// totalRequests counts every request, matched or not.
+var totalRequests atomic.Int64
+
+type StatsResponse struct {
+	StartTime     string           `json:"start_time"`
+	Timestamp     string           `json:"timestamp"`
+	UptimeSeconds float64          `json:"uptime_seconds"`
+	TotalRequests int64            `json:"total_requests"`
+	Endpoints     map[string]int64 `json:"endpoints"`
+}
+
+// countRequest records a hit for endpoint, as named by endpointLabel.
+func countRequest(endpoint string) {
+	totalRequests.Add(1)
+	counter, _ := requestCounts.LoadOrStore(endpoint, new(atomic.Int64))
+	counter.(*atomic.Int64).Add(1)
+}
+
+// statsHandler reports per-endpoint request counts since startup, a
+// lightweight alternative to scraping /metrics.
+func statsHandler(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	response := StatsResponse{
+		StartTime:     startTime.UTC().Format(time.RFC3339),
+		Timestamp:     now.UTC().Format(time.RFC3339),
+		UptimeSeconds: now.Sub(startTime).Seconds(),
+		TotalRequests: totalRequests.Load(),
+		Endpoints:     make(map[string]int64),
+	}
+	requestCounts.Range(func(key, value any) bool {
+		response.Endpoints[key.(string)] = value.(*atomic.Int64).Load()
+		return true
+	})
+	writeJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// TestStatsLabelsRequestsWithRequestTimeout guards against counting requests
+// outside limitRequestTime, where the route pattern the mux sets on the
+// TimeoutHandler's copy of the request is never seen.
+func TestStatsLabelsRequestsWithRequestTimeout(t *testing.T) {
+	t.Setenv("REQUEST_TIMEOUT", "") // default 30s, so TimeoutHandler is active
+	t.Setenv("ACCESS_LOG", "false")
+	t.Setenv("AUTH_TOKEN", "")
+	t.Setenv("RATE_LIMIT", "")
+
+	var err error
+	routes, err = buildRoutes([]string{"/health"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	registerRoutes(mux)
+	handler := serverHandler(mux)
+	requestCounts.Clear()
+	totalRequests.Store(0)
+
+	for _, path := range []string{"/health", "/sysinfo", "/sysinfo", "/no-such-path"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/stats", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("/stats status = %d, want 200", rec.Code)
+	}
+
+	var stats StatsResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &stats); err != nil {
+		t.Fatalf("decoding /stats: %v", err)
+	}
+	// /stats counts itself only after writing its response.
+	want := map[string]int64{"/health": 1, "/sysinfo": 2, "unmatched": 1}
+	if len(stats.Endpoints) != len(want) {
+		t.Errorf("endpoints = %v, want %v", stats.Endpoints, want)
+	}
+	for endpoint, count := range want {
+		if stats.Endpoints[endpoint] != count {
+			t.Errorf("endpoints[%q] = %d, want %d (all: %v)", endpoint, stats.Endpoints[endpoint], count, stats.Endpoints)
+		}
+	}
+	if stats.TotalRequests != 4 {
+		t.Errorf("total_requests = %d, want 4", stats.TotalRequests)
+	}
+
+	// /metrics must agree: the unknown path fell through to the "/" catch-all
+	// but is labeled unmatched there too.
+	if got := testutil.ToFloat64(httpRequestsTotal.WithLabelValues("unmatched", "404")); got != 1 {
+		t.Errorf(`requests_total{endpoint="unmatched",status="404"} = %v, want 1`, got)
+	}
+	if got := testutil.ToFloat64(httpRequestsTotal.WithLabelValues("/", "404")); got != 0 {
+		t.Errorf(`requests_total{endpoint="/",status="404"} = %v, want 0`, got)
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+type MemoryStats struct {
+	HeapAllocBytes uint64 `json:"heap_alloc_bytes"`
+	HeapSysBytes   uint64 `json:"heap_sys_bytes"`
+	SysBytes       uint64 `json:"sys_bytes"`
+	NumGC          uint32 `json:"num_gc"`
+}
+
+type CgroupLimits struct {
+	Version          string  `json:"version,omitempty"`
+	MemoryLimitBytes int64   `json:"memory_limit_bytes,omitempty"`
+	MemoryUsageBytes int64   `json:"memory_usage_bytes,omitempty"`
+	CPULimitCores    float64 `json:"cpu_limit_cores,omitempty"`
+	MemoryUnlimited  bool    `json:"memory_unlimited,omitempty"`
+	CPUUnlimited     bool    `json:"cpu_unlimited,omitempty"`
+}
+
+type DiskUsage struct {
+	Path       string  `json:"path"`
+	TotalBytes uint64  `json:"total_bytes"`
+	FreeBytes  uint64  `json:"free_bytes"`
+	UsedPct    float64 `json:"used_pct"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// defaultGoroutineWarn is the goroutine count above which /sysinfo flags a
+// probable leak when GOROUTINE_WARN is unset.
+const defaultGoroutineWarn = 1000
+
+type SysInfoResponse struct {
+	Goroutines       int          `json:"goroutines"`
+	GoroutineWarn    int          `json:"goroutine_warn_threshold"`
+	GoroutineWarning bool         `json:"goroutine_warning"`
+	NumCPU           int          `json:"num_cpu"`
+	GoMemory         MemoryStats  `json:"go_memory"`
+	Cgroup           CgroupLimits `json:"cgroup"`
+	Disks            []DiskUsage  `json:"disks"`
+}
+
+// readCgroupValue returns the trimmed contents of a cgroup file.
+func readCgroupValue(path string) (string, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", false
+	}
+	return strings.TrimSpace(string(data)), true
+}
+
+// readCgroupLimits reads the memory and CPU limits the platform applied,
+// supporting both cgroup v2 and v1 layouts.
+func readCgroupLimits() CgroupLimits {
+	var limits CgroupLimits
+
+	if val, ok := readCgroupValue("/sys/fs/cgroup/memory.max"); ok {
+		limits.Version = "v2"
+		if val == "max" {
+			limits.MemoryUnlimited = true
+		} else {
+			limits.MemoryLimitBytes, _ = strconv.ParseInt(val, 10, 64)
+		}
+		if val, ok := readCgroupValue("/sys/fs/cgroup/memory.current"); ok {
+			limits.MemoryUsageBytes, _ = strconv.ParseInt(val, 10, 64)
+		}
+		if val, ok := readCgroupValue("/sys/fs/cgroup/cpu.max"); ok {
+			fields := strings.Fields(val)
+			if len(fields) == 2 && fields[0] != "max" {
+				quota, _ := strconv.ParseFloat(fields[0], 64)
+				period, _ := strconv.ParseFloat(fields[1], 64)
+				if period > 0 {
+					limits.CPULimitCores = quota / period
+				}
+			} else {
+				limits.CPUUnlimited = true
+			}
+		}
+		return limits
+	}
+
+	if val, ok := readCgroupValue("/sys/fs/cgroup/memory/memory.limit_in_bytes"); ok {
+		limits.Version = "v1"
+		limits.MemoryLimitBytes, _ = strconv.ParseInt(val, 10, 64)
+		// v1 reports "unlimited" as a huge page-aligned number.
+		if limits.MemoryLimitBytes >= 1<<62 {
+			limits.MemoryLimitBytes = 0
+			limits.MemoryUnlimited = true
+		}
+		if val, ok := readCgroupValue("/sys/fs/cgroup/memory/memory.usage_in_bytes"); ok {
+			limits.MemoryUsageBytes, _ = strconv.ParseInt(val, 10, 64)
+		}
+		quotaVal, _ := readCgroupValue("/sys/fs/cgroup/cpu/cpu.cfs_quota_us")
+		periodVal, _ := readCgroupValue("/sys/fs/cgroup/cpu/cpu.cfs_period_us")
+		quota, _ := strconv.ParseFloat(quotaVal, 64)
+		period, _ := strconv.ParseFloat(periodVal, 64)
+		if quota > 0 && period > 0 {
+			limits.CPULimitCores = quota / period
+		} else {
+			limits.CPUUnlimited = true
+		}
+	}
+	return limits
+}
+
+// diskUsage reports filesystem capacity for path.
+func diskUsage(path string) DiskUsage {
+	usage := DiskUsage{Path: path}
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		usage.Error = err.Error()
+		return usage
+	}
+	usage.TotalBytes = stat.Blocks * uint64(stat.Bsize)
+	usage.FreeBytes = stat.Bavail * uint64(stat.Bsize)
+	if usage.TotalBytes > 0 {
+		usage.UsedPct = float64(usage.TotalBytes-usage.FreeBytes) / float64(usage.TotalBytes) * 100
+	}
+	return usage
+}
+
+// getGoroutineWarn reads GOROUTINE_WARN (default 1000).
+func getGoroutineWarn() int {
+	if val := os.Getenv("GOROUTINE_WARN"); val != "" {
+		if n, err := strconv.Atoi(val); err == nil && n > 0 {
+			return n
+		}
+	}
+	return defaultGoroutineWarn
+}
+
+func sysinfoHandler(w http.ResponseWriter, r *http.Request) {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	// A goroutine count that keeps growing usually means a check handler
+	// is leaving connections or goroutines behind.
+	goroutines, threshold := runtime.NumGoroutine(), getGoroutineWarn()
+	if goroutines > threshold {
+		slog.Warn("goroutine count above GOROUTINE_WARN", "goroutines", goroutines, "threshold", threshold)
+	}
+
+	response := SysInfoResponse{
+		Goroutines:       goroutines,
+		GoroutineWarn:    threshold,
+		GoroutineWarning: goroutines > threshold,
+		NumCPU:           runtime.NumCPU(),
+		GoMemory: MemoryStats{
+			HeapAllocBytes: mem.HeapAlloc,
+			HeapSysBytes:   mem.HeapSys,
+			SysBytes:       mem.Sys,
+			NumGC:          mem.NumGC,
+		},
+		Cgroup: readCgroupLimits(),
+		Disks:  []DiskUsage{diskUsage("/"), diskUsage("/tmp")},
+	}
+	writeJSON(w, http.StatusOK, response)
+}
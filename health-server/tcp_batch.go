@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+// maxTCPBatch caps how many targets one /check/tcp-batch request may dial.
+const maxTCPBatch = 50
+
+// tcpBatchWorkers bounds how many dials a batch has in flight at once.
+const tcpBatchWorkers = 10
+
+// maxTCPBatchBodyBytes bounds the size of a /check/tcp-batch request body.
+const maxTCPBatchBodyBytes = 64 << 10
+
+type TCPTarget struct {
+	Host string `json:"host"`
+	Port int    `json:"port"`
+}
+
+type TCPBatchResponse struct {
+	Total       int         `json:"total"`
+	Reachable   int         `json:"reachable"`
+	Unreachable int         `json:"unreachable"`
+	Results     []TCPResult `json:"results"`
+}
+
+// tcpBatchHandler dials every {host, port} in the POSTed JSON array and
+// reports each one's reachability, in request order. Each dial is bounded
+// by CHECK_TIMEOUT (or ?timeout=).
+func tcpBatchHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		writeError(w, http.StatusMethodNotAllowed, `use POST with a JSON body [{"host": "...", "port": 5432}, ...]`)
+		return
+	}
+	var targets []TCPTarget
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxTCPBatchBodyBytes)).Decode(&targets); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+	if len(targets) == 0 {
+		writeError(w, http.StatusBadRequest, "at least one target is required")
+		return
+	}
+	if len(targets) > maxTCPBatch {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("at most %d targets may be checked at once", maxTCPBatch))
+		return
+	}
+	for i, target := range targets {
+		if target.Host == "" {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("target %d: host is required", i))
+			return
+		}
+		if target.Port < 1 || target.Port > 65535 {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("target %d: port must be a number between 1 and 65535", i))
+			return
+		}
+	}
+
+	timeout := checkTimeout(r.Context())
+	response := TCPBatchResponse{Total: len(targets), Results: make([]TCPResult, len(targets))}
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for n := 0; n < min(tcpBatchWorkers, len(targets)); n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				response.Results[i] = dialTCP(r.Context(), targets[i].Host, strconv.Itoa(targets[i].Port), timeout)
+			}
+		}()
+	}
+	for i := range targets {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	for _, result := range response.Results {
+		if result.Reachable {
+			response.Reachable++
+		} else {
+			response.Unreachable++
+		}
+	}
+	writeCheckResult(w, response.Unreachable == 0, response)
+}
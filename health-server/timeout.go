@@ -0,0 +1,39 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// untimedPaths stream for as long as the client stays connected (or, for
+// /exec, up to EXEC_TIMEOUT), so REQUEST_TIMEOUT doesn't apply to them.
+var untimedPaths = map[string]bool{
+	"/exec":        true,
+	"/logs/stream": true,
+}
+
+// limitRequestTime answers 503 for any request whose handler runs longer
+// than REQUEST_TIMEOUT (default 30s, 0 disables), as a safety net for a
+// check whose own timeout doesn't fire. The handler's context is canceled
+// at the same moment.
+func limitRequestTime(next http.Handler) http.Handler {
+	timeout := getEnvDuration("REQUEST_TIMEOUT", 30*time.Second)
+	if timeout <= 0 {
+		return next
+	}
+	body, _ := json.Marshal(ErrorResponse{Error: fmt.Sprintf("request exceeded REQUEST_TIMEOUT (%s)", timeout)})
+	timed := http.TimeoutHandler(next, timeout, string(body))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if untimedPaths[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+		// TimeoutHandler writes its error body without a Content-Type.
+		// Every handler sets its own, which replaces this one when the
+		// handler finishes in time.
+		w.Header().Set("Content-Type", "application/json")
+		timed.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"os"
+	"time"
+)
+
+// tlsSettings describes how the server should terminate TLS. CertFile and
+// KeyFile are used when both are set; otherwise Config carries a generated
+// self-signed certificate. A nil *tlsSettings means plain HTTP.
+type tlsSettings struct {
+	CertFile string
+	KeyFile  string
+	Config   *tls.Config
+}
+
+// getTLSSettings reads TLS_CERT_FILE/TLS_KEY_FILE, falling back to an
+// in-memory self-signed certificate when TLS_SELF_SIGNED=true.
+func getTLSSettings() (*tlsSettings, error) {
+	certFile := os.Getenv("TLS_CERT_FILE")
+	keyFile := os.Getenv("TLS_KEY_FILE")
+	if certFile != "" && keyFile != "" {
+		return &tlsSettings{CertFile: certFile, KeyFile: keyFile}, nil
+	}
+	if os.Getenv("TLS_SELF_SIGNED") != "true" {
+		return nil, nil
+	}
+
+	cert, err := generateSelfSignedCert()
+	if err != nil {
+		return nil, err
+	}
+	return &tlsSettings{Config: &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}}, nil
+}
+
+// generateSelfSignedCert creates a short-lived ECDSA certificate for
+// localhost and the container hostname.
+func generateSelfSignedCert() (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	dnsNames := []string{"localhost"}
+	if hostname, err := os.Hostname(); err == nil {
+		dnsNames = append(dnsNames, hostname)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"do-app-debug-container"}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(365 * 24 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		DNSNames:              dnsNames,
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
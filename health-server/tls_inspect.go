@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type CertificateInfo struct {
+	Subject      string   `json:"subject"`
+	Issuer       string   `json:"issuer"`
+	DNSNames     []string `json:"dns_names,omitempty"`
+	IPAddresses  []string `json:"ip_addresses,omitempty"`
+	SerialNumber string   `json:"serial_number"`
+	NotBefore    string   `json:"not_before"`
+	NotAfter     string   `json:"not_after"`
+	DaysLeft     int      `json:"days_left"`
+	IsCA         bool     `json:"is_ca"`
+}
+
+type TLSInspectResult struct {
+	Host              string            `json:"host"`
+	Port              string            `json:"port"`
+	Connected         bool              `json:"connected"`
+	TLSVersion        string            `json:"tls_version,omitempty"`
+	CipherSuite       string            `json:"cipher_suite,omitempty"`
+	Verified          bool              `json:"verified"`
+	VerificationError string            `json:"verification_error,omitempty"`
+	Certificates      []CertificateInfo `json:"certificates"`
+	Error             string            `json:"error,omitempty"`
+}
+
+func describeCertificate(cert *x509.Certificate) CertificateInfo {
+	info := CertificateInfo{
+		Subject:      cert.Subject.String(),
+		Issuer:       cert.Issuer.String(),
+		DNSNames:     cert.DNSNames,
+		SerialNumber: cert.SerialNumber.String(),
+		NotBefore:    cert.NotBefore.UTC().Format(time.RFC3339),
+		NotAfter:     cert.NotAfter.UTC().Format(time.RFC3339),
+		DaysLeft:     int(time.Until(cert.NotAfter).Hours() / 24),
+		IsCA:         cert.IsCA,
+	}
+	for _, ip := range cert.IPAddresses {
+		info.IPAddresses = append(info.IPAddresses, ip.String())
+	}
+	return info
+}
+
+// verifyChain checks the presented chain against the system roots, the way
+// a client with default settings would.
+func verifyChain(host string, certs []*x509.Certificate) error {
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := certs[0].Verify(x509.VerifyOptions{
+		DNSName:       host,
+		Intermediates: intermediates,
+	})
+	return err
+}
+
+// inspectTLS completes a TLS handshake with host:port and describes the
+// peer's certificate chain. Verification is performed separately so the
+// chain is reported even when it wouldn't be trusted.
+func inspectTLS(ctx context.Context, host, port string, skipVerify bool) TLSInspectResult {
+	result := TLSInspectResult{Host: host, Port: port, Certificates: []CertificateInfo{}}
+
+	dialer := &tls.Dialer{Config: &tls.Config{
+		ServerName:         host,
+		InsecureSkipVerify: true,
+	}}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(host, port))
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	defer conn.Close()
+	result.Connected = true
+
+	state := conn.(*tls.Conn).ConnectionState()
+	result.TLSVersion = tls.VersionName(state.Version)
+	result.CipherSuite = tls.CipherSuiteName(state.CipherSuite)
+	for _, cert := range state.PeerCertificates {
+		result.Certificates = append(result.Certificates, describeCertificate(cert))
+	}
+
+	if skipVerify || len(state.PeerCertificates) == 0 {
+		return result
+	}
+	if err := verifyChain(host, state.PeerCertificates); err != nil {
+		result.VerificationError = err.Error()
+	} else {
+		result.Verified = true
+	}
+	return result
+}
+
+// tlsInspectHandler serves /tls?host=db.example.com&port=25060[&insecure=true].
+func tlsInspectHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	host, port := query.Get("host"), query.Get("port")
+	if port == "" {
+		port = "443"
+	}
+	if host == "" {
+		writeError(w, http.StatusBadRequest, "missing host parameter")
+		return
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		writeError(w, http.StatusBadRequest, "port must be a number between 1 and 65535")
+		return
+	}
+	timeout, err := probeTimeout(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+	result := inspectTLS(ctx, host, port, query.Get("insecure") == "true")
+	writeCheckResult(w, result.Connected, result)
+}
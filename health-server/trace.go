@@ -0,0 +1,255 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+)
+
+const (
+	// defaultTraceHops and maxTraceHops bound the TTLs /trace probes.
+	defaultTraceHops = 30
+	maxTraceHops     = 64
+	// traceHopTimeout is how long each hop gets to answer.
+	traceHopTimeout = time.Second
+)
+
+type TraceHop struct {
+	TTL     int     `json:"ttl"`
+	Address string  `json:"address"`
+	RTTMs   float64 `json:"rtt_ms,omitempty"`
+	Reached bool    `json:"reached,omitempty"`
+	Note    string  `json:"note,omitempty"`
+}
+
+type TraceResult struct {
+	Host    string     `json:"host"`
+	Address string     `json:"address"`
+	Method  string     `json:"method"`
+	Port    string     `json:"port,omitempty"`
+	Hops    []TraceHop `json:"hops"`
+	Reached bool       `json:"reached"`
+	Note    string     `json:"note,omitempty"`
+	Error   string     `json:"error,omitempty"`
+}
+
+// traceICMP sends ICMP echo requests with increasing TTLs and records which
+// router answers each one with Time Exceeded. It needs a raw socket, so it
+// fails without CAP_NET_RAW.
+func traceICMP(ctx context.Context, dst net.IP, maxHops int, result *TraceResult) error {
+	conn, err := icmp.ListenPacket("ip4:icmp", "0.0.0.0")
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	pc := conn.IPv4PacketConn()
+	id := os.Getpid() & 0xffff
+	buf := make([]byte, 1500)
+
+	for ttl := 1; ttl <= maxHops && ctx.Err() == nil; ttl++ {
+		if err := pc.SetTTL(ttl); err != nil {
+			return err
+		}
+		msg := icmp.Message{
+			Type: ipv4.ICMPTypeEcho,
+			Body: &icmp.Echo{ID: id, Seq: ttl, Data: []byte("do-app-debug-trace")},
+		}
+		packet, err := msg.Marshal(nil)
+		if err != nil {
+			return err
+		}
+		start := time.Now()
+		if _, err := conn.WriteTo(packet, &net.IPAddr{IP: dst}); err != nil {
+			return err
+		}
+		conn.SetReadDeadline(start.Add(traceHopTimeout))
+
+		hop := TraceHop{TTL: ttl, Address: "*"}
+		for {
+			n, peer, err := conn.ReadFrom(buf)
+			if err != nil {
+				break
+			}
+			reply, err := icmp.ParseMessage(1, buf[:n])
+			if err != nil {
+				continue
+			}
+			matched := false
+			switch body := reply.Body.(type) {
+			case *icmp.Echo:
+				matched = reply.Type == ipv4.ICMPTypeEchoReply && body.ID == id && body.Seq == ttl
+				hop.Reached = matched
+			case *icmp.TimeExceeded:
+				matched = quotedEchoMatches(body.Data, id, ttl)
+			case *icmp.DstUnreach:
+				matched = quotedEchoMatches(body.Data, id, ttl)
+				hop.Reached = matched
+				hop.Note = "destination unreachable"
+			}
+			if matched {
+				hop.Address = peer.String()
+				hop.RTTMs = latencyMs(time.Since(start))
+				break
+			}
+		}
+		result.Hops = append(result.Hops, hop)
+		if hop.Reached {
+			result.Reached = true
+			return nil
+		}
+	}
+	return nil
+}
+
+// quotedEchoMatches reports whether the original datagram quoted in an ICMP
+// error is our echo request with the given ID and sequence number.
+func quotedEchoMatches(data []byte, id, seq int) bool {
+	if len(data) < 1 {
+		return false
+	}
+	headerLen := int(data[0]&0x0f) * 4
+	if len(data) < headerLen+8 {
+		return false
+	}
+	echo := data[headerLen:]
+	return int(binary.BigEndian.Uint16(echo[4:6])) == id && int(binary.BigEndian.Uint16(echo[6:8])) == seq
+}
+
+// traceTCP opens TCP connections with increasing TTLs. Without a raw socket
+// the routers that drop each SYN can't be identified, but a hop that answers
+// with an ICMP error, a refused connection, or a completed handshake still
+// shows how far the path gets.
+func traceTCP(ctx context.Context, dst net.IP, port string, maxHops int, result *TraceResult) {
+	addr := net.JoinHostPort(dst.String(), port)
+	for ttl := 1; ttl <= maxHops && ctx.Err() == nil; ttl++ {
+		dialer := net.Dialer{
+			Timeout: traceHopTimeout,
+			Control: func(network, address string, c syscall.RawConn) error {
+				var sockErr error
+				err := c.Control(func(fd uintptr) {
+					sockErr = setTraceTTL(int(fd), dst.To4() == nil, ttl)
+				})
+				if err != nil {
+					return err
+				}
+				return sockErr
+			},
+		}
+
+		hop := TraceHop{TTL: ttl, Address: "*"}
+		start := time.Now()
+		conn, err := dialer.DialContext(ctx, "tcp", addr)
+		elapsed := latencyMs(time.Since(start))
+		switch {
+		case err == nil:
+			conn.Close()
+			hop.Address, hop.RTTMs, hop.Reached = dst.String(), elapsed, true
+			hop.Note = "connected"
+		case errors.Is(err, syscall.ECONNREFUSED):
+			hop.Address, hop.RTTMs, hop.Reached = dst.String(), elapsed, true
+			hop.Note = "connection refused"
+		case errors.Is(err, syscall.EHOSTUNREACH), errors.Is(err, syscall.ENETUNREACH):
+			hop.RTTMs = elapsed
+			hop.Note = "router answered (address unavailable without raw sockets)"
+		}
+		result.Hops = append(result.Hops, hop)
+		if hop.Reached {
+			result.Reached = true
+			return
+		}
+	}
+}
+
+// traceHandler runs a traceroute-style probe to ?host=. It uses ICMP when a
+// raw socket is available and falls back to a TCP trace to ?port= (default
+// 443) otherwise; ?method=tcp forces the TCP trace.
+func traceHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	host := query.Get("host")
+	port := query.Get("port")
+	if port == "" {
+		port = "443"
+	}
+	maxHops := defaultTraceHops
+	if val := query.Get("max_hops"); val != "" {
+		n, err := strconv.Atoi(val)
+		if err != nil || n < 1 || n > maxTraceHops {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("max_hops must be a number between 1 and %d", maxTraceHops))
+			return
+		}
+		maxHops = n
+	}
+	if host == "" {
+		writeError(w, http.StatusBadRequest, "host parameter is required")
+		return
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		writeError(w, http.StatusBadRequest, "port must be a number between 1 and 65535")
+		return
+	}
+
+	result := TraceResult{Host: host, Hops: []TraceHop{}}
+	// Stop probing a couple of hops' time before REQUEST_TIMEOUT (the request
+	// context's deadline) so the hops found so far are still returned.
+	budget := time.Duration(maxHops)*traceHopTimeout + 5*time.Second
+	if deadline, ok := r.Context().Deadline(); ok {
+		budget = min(budget, time.Until(deadline)-2*traceHopTimeout)
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), budget)
+	defer cancel()
+
+	ips, err := net.DefaultResolver.LookupIP(ctx, "ip", host)
+	if err != nil || len(ips) == 0 {
+		result.Error = fmt.Sprintf("could not resolve %s: %v", host, err)
+		writeCheckResult(w, false, result)
+		return
+	}
+	dst := ips[0]
+	for _, ip := range ips {
+		if ip.To4() != nil {
+			dst = ip
+			break
+		}
+	}
+	result.Address = dst.String()
+
+	if query.Get("method") != "tcp" && dst.To4() != nil {
+		result.Method = "icmp"
+		err := traceICMP(ctx, dst, maxHops, &result)
+		if err == nil {
+			noteTraceCutShort(ctx, maxHops, &result)
+			writeCheckResult(w, result.Reached, result)
+			return
+		}
+		result.Note = "ICMP trace unavailable (" + err.Error() + "); fell back to TCP"
+		result.Hops = []TraceHop{}
+	}
+	result.Method = "tcp"
+	result.Port = port
+	traceTCP(ctx, dst, port, maxHops, &result)
+	noteTraceCutShort(ctx, maxHops, &result)
+	writeCheckResult(w, result.Reached, result)
+}
+
+// noteTraceCutShort explains a trace that ran out of time before reaching
+// the host or max_hops.
+func noteTraceCutShort(ctx context.Context, maxHops int, result *TraceResult) {
+	if result.Reached || len(result.Hops) >= maxHops || ctx.Err() == nil {
+		return
+	}
+	note := fmt.Sprintf("stopped after %d of %d hops when the time limit ran out (REQUEST_TIMEOUT); lower max_hops for a complete trace", len(result.Hops), maxHops)
+	if result.Note != "" {
+		note = result.Note + "; " + note
+	}
+	result.Note = note
+}
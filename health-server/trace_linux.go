@@ -0,0 +1,19 @@
+package main
+
+import "syscall"
+
+// setTraceTTL sets the hop limit on a trace socket and enables IP_RECVERR so
+// an ICMP error from a router fails the connect immediately instead of
+// leaving it to time out.
+func setTraceTTL(fd int, ipv6 bool, ttl int) error {
+	if ipv6 {
+		if err := syscall.SetsockoptInt(fd, syscall.IPPROTO_IPV6, syscall.IPV6_UNICAST_HOPS, ttl); err != nil {
+			return err
+		}
+		return syscall.SetsockoptInt(fd, syscall.IPPROTO_IPV6, syscall.IPV6_RECVERR, 1)
+	}
+	if err := syscall.SetsockoptInt(fd, syscall.IPPROTO_IP, syscall.IP_TTL, ttl); err != nil {
+		return err
+	}
+	return syscall.SetsockoptInt(fd, syscall.IPPROTO_IP, syscall.IP_RECVERR, 1)
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+
+package main
+
+import "syscall"
+
+// setTraceTTL sets the hop limit on a trace socket. ICMP errors aren't
+// surfaced on connect outside Linux, so silent hops simply time out.
+func setTraceTTL(fd int, ipv6 bool, ttl int) error {
+	if ipv6 {
+		return syscall.SetsockoptInt(fd, syscall.IPPROTO_IPV6, syscall.IPV6_UNICAST_HOPS, ttl)
+	}
+	return syscall.SetsockoptInt(fd, syscall.IPPROTO_IP, syscall.IP_TTL, ttl)
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"net/http"
+	"runtime"
+)
+
+// Build metadata, stamped at build time via
+// -ldflags "-X main.version=... -X main.commit=... -X main.buildDate=...".
+var (
+	version   = "dev"
+	commit    = "unknown"
+	buildDate = "unknown"
+)
+
+type VersionResponse struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"build_date"`
+	GoVersion string `json:"go_version"`
+}
+
+func versionHandler(w http.ResponseWriter, r *http.Request) {
+	response := VersionResponse{
+		Version:   version,
+		Commit:    commit,
+		BuildDate: buildDate,
+		GoVersion: runtime.Version(),
+	}
+	writeJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// waitPollInterval is how often the startup wait re-runs failing checks.
+const waitPollInterval = 2 * time.Second
+
+// startupPhase describes what startup is waiting on while startupComplete is
+// false; /ready reports it.
+var startupPhase = struct {
+	sync.RWMutex
+	msg string
+}{msg: "runtime detection in progress"}
+
+func setStartupPhase(msg string) {
+	startupPhase.Lock()
+	defer startupPhase.Unlock()
+	startupPhase.msg = msg
+}
+
+func getStartupPhase() string {
+	startupPhase.RLock()
+	defer startupPhase.RUnlock()
+	return startupPhase.msg
+}
+
+// waitForChecks resolves WAIT_FOR (comma-separated database check names)
+// against dbChecks.
+func waitForChecks() ([]dbCheck, error) {
+	var checks []dbCheck
+	for _, name := range strings.Split(os.Getenv("WAIT_FOR"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		found := false
+		for _, check := range dbChecks {
+			if check.Name == name {
+				checks = append(checks, check)
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("WAIT_FOR: unknown dependency %q (want postgres, redis, mysql, mongodb, kafka or opensearch)", name)
+		}
+	}
+	return checks, nil
+}
+
+// waitForDependencies runs the WAIT_FOR checks every waitPollInterval until
+// they all pass, WAIT_TIMEOUT (default 60s, 0 for no limit) expires, or ctx
+// is cancelled. It returns an error naming the checks still failing.
+func waitForDependencies(ctx context.Context, checks []dbCheck) error {
+	if len(checks) == 0 {
+		return nil
+	}
+	timeout := getEnvDuration("WAIT_TIMEOUT", 60*time.Second)
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	pending := make(map[string]dbCheck)
+	for _, check := range checks {
+		pending[check.Name] = check
+	}
+	lastErrors := make(map[string]string)
+	for {
+		names := make([]string, 0, len(pending))
+		for name := range pending {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		setStartupPhase("waiting for dependencies: " + strings.Join(names, ", "))
+		slog.Info("waiting for dependencies", "pending", names, "elapsed", time.Since(start).Round(time.Second).String())
+
+		// The goroutines only read their own check; pending is updated once
+		// they have all finished.
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		var ready []string
+		for _, name := range names {
+			wg.Add(1)
+			go func(name string, check dbCheck) {
+				defer wg.Done()
+				ok, errMsg := false, "not configured"
+				if check.Configured() {
+					checkCtx, cancel := context.WithTimeout(ctx, checkTimeout(ctx))
+					result, passed := check.Run(checkCtx)
+					cancel()
+					ok, errMsg = passed, checkResultError(result)
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				if ok {
+					ready = append(ready, name)
+					slog.Info("dependency ready", "dependency", name, "elapsed", time.Since(start).Round(time.Millisecond).String())
+					return
+				}
+				lastErrors[name] = errMsg
+			}(name, pending[name])
+		}
+		wg.Wait()
+		for _, name := range ready {
+			delete(pending, name)
+		}
+		if len(pending) == 0 {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			var failed []string
+			for name := range pending {
+				failed = append(failed, fmt.Sprintf("%s (%s)", name, lastErrors[name]))
+			}
+			sort.Strings(failed)
+			return fmt.Errorf("dependencies not ready after %s: %s", time.Since(start).Round(time.Second), strings.Join(failed, "; "))
+		case <-time.After(waitPollInterval):
+		}
+	}
+}
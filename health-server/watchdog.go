@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+// watchdogRequestTimeout bounds each self-request the watchdog makes.
+const watchdogRequestTimeout = 5 * time.Second
+
+// watchdogFailures reads WATCHDOG_FAILURES, the consecutive failed
+// self-requests that trip the watchdog (default 3).
+func watchdogFailures() int {
+	if val := os.Getenv("WATCHDOG_FAILURES"); val != "" {
+		if n, err := strconv.Atoi(val); err == nil && n >= 1 {
+			return n
+		}
+	}
+	return 3
+}
+
+// healthRoutePath returns the first path the liveness check is served on.
+func healthRoutePath() string {
+	return healthPaths[0]
+}
+
+// selfClient returns a client and base URL that reach the server through
+// ln, the way an outside caller would.
+func selfClient(ln net.Listener, scheme string) (*http.Client, string) {
+	transport := &http.Transport{
+		// The server's certificate won't name the loopback address.
+		TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+		DisableKeepAlives: true,
+	}
+	host := ln.Addr().String()
+	switch addr := ln.Addr().(type) {
+	case *net.UnixAddr:
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", addr.Name)
+		}
+		host = "localhost"
+	case *net.TCPAddr:
+		if addr.IP.IsUnspecified() {
+			host = net.JoinHostPort("127.0.0.1", strconv.Itoa(addr.Port))
+		}
+	}
+	return &http.Client{Transport: transport, Timeout: watchdogRequestTimeout}, scheme + "://" + host
+}
+
+// startWatchdog requests the health path through the server's own listener
+// every WATCHDOG_INTERVAL (default 30s) when WATCHDOG=true. After
+// WATCHDOG_FAILURES consecutive requests get no response it logs an error
+// and, unless WATCHDOG_EXIT=false, exits so the platform restarts the
+// container. A 503 from /health is a deliberate answer and counts as alive;
+// the watchdog is for an HTTP stack that holds the port but stops serving.
+func startWatchdog(ctx context.Context, ln net.Listener, scheme string) {
+	if os.Getenv("WATCHDOG") != "true" {
+		return
+	}
+	interval := getEnvDuration("WATCHDOG_INTERVAL", 30*time.Second)
+	if interval <= 0 {
+		slog.Warn("WATCHDOG_INTERVAL must be positive; watchdog disabled")
+		return
+	}
+	threshold := watchdogFailures()
+	exit := os.Getenv("WATCHDOG_EXIT") != "false"
+	client, baseURL := selfClient(ln, scheme)
+	target := baseURL + healthRoutePath()
+
+	probe := func() error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("User-Agent", "do-app-debug-container-watchdog")
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusServiceUnavailable {
+			return fmt.Errorf("unexpected status %s", resp.Status)
+		}
+		return nil
+	}
+
+	slog.Info("watchdog started", "target", target, "interval", interval.String(), "failure_threshold", threshold)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		failures := 0
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			err := probe()
+			if ctx.Err() != nil {
+				return
+			}
+			if err == nil {
+				if failures > 0 {
+					slog.Info("watchdog self-check recovered", "after_failures", failures)
+				}
+				failures = 0
+				continue
+			}
+			failures++
+			slog.Warn("watchdog self-check failed", "error", err, "consecutive_failures", failures, "threshold", threshold)
+			if failures < threshold {
+				continue
+			}
+			slog.Error("health server is not responding to its own requests", "consecutive_failures", failures, "exit", exit)
+			if exit {
+				os.Exit(1)
+			}
+			failures = 0
+		}
+	}()
+}
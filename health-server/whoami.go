@@ -0,0 +1,65 @@
+package main
+
+import (
+	"net/http"
+	"os"
+)
+
+// WhoAmIResponse describes the App Platform component this container runs
+// as. App Platform exposes most of these through bindable variables, e.g.
+// APP_URL=${APP_URL} or COMPONENT_NAME=${_self.COMPONENT_NAME}.
+type WhoAmIResponse struct {
+	AppID            string   `json:"app_id,omitempty"`
+	AppURL           string   `json:"app_url,omitempty"`
+	AppDomain        string   `json:"app_domain,omitempty"`
+	Component        string   `json:"component,omitempty"`
+	ComponentURL     string   `json:"component_url,omitempty"`
+	PrivateDomain    string   `json:"private_domain,omitempty"`
+	Region           string   `json:"region,omitempty"`
+	InstanceSize     string   `json:"instance_size,omitempty"`
+	Hostname         string   `json:"hostname,omitempty"`
+	ContainerType    string   `json:"container_type"`
+	CPULimitCores    float64  `json:"cpu_limit_cores,omitempty"`
+	MemoryLimitBytes int64    `json:"memory_limit_bytes,omitempty"`
+	Unset            []string `json:"unset,omitempty"`
+}
+
+// whoamiEnv returns the first of names that is set. When none is, the
+// first name is recorded in unset so the response says what to bind.
+func whoamiEnv(unset *[]string, names ...string) string {
+	for _, name := range names {
+		if val := os.Getenv(name); val != "" {
+			return val
+		}
+	}
+	*unset = append(*unset, names[0])
+	return ""
+}
+
+// whoamiHandler reports where the container is running: app, component,
+// region and instance size. None of these are secrets, so nothing is
+// redacted.
+func whoamiHandler(w http.ResponseWriter, r *http.Request) {
+	var unset []string
+	response := WhoAmIResponse{
+		AppID:         whoamiEnv(&unset, "APP_ID"),
+		AppURL:        whoamiEnv(&unset, "APP_URL"),
+		AppDomain:     whoamiEnv(&unset, "APP_DOMAIN"),
+		Component:     whoamiEnv(&unset, "COMPONENT_NAME", "DO_COMPONENT_NAME"),
+		ComponentURL:  whoamiEnv(&unset, "COMPONENT_URL"),
+		PrivateDomain: whoamiEnv(&unset, "PRIVATE_DOMAIN"),
+		Region:        whoamiEnv(&unset, "REGION", "DO_REGION", "APP_REGION"),
+		InstanceSize:  whoamiEnv(&unset, "INSTANCE_SIZE", "INSTANCE_SIZE_SLUG"),
+		ContainerType: getContainerType(),
+		Unset:         unset,
+	}
+	// App Platform instances are pods, so the hostname identifies the
+	// instance when a component runs more than one.
+	response.Hostname, _ = os.Hostname()
+	// The cgroup limits reflect the instance size even when it isn't bound.
+	limits := readCgroupLimits()
+	response.CPULimitCores = limits.CPULimitCores
+	response.MemoryLimitBytes = limits.MemoryLimitBytes
+
+	writeJSON(w, http.StatusOK, response)
+}
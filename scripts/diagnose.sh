@@ -1,5 +1,6 @@
 #!/bin/bash
 # Full system diagnostic report for App Platform debugging
+# Description: Full system diagnostic report
 # Works with both Python and Node.js runtimes
 
 set -euo pipefail
@@ -1,5 +1,6 @@
 #!/bin/bash
 # Startup script for DigitalOcean App Platform Debug Container
+# Description: Container startup banner and health server launcher
 # Displays helpful information and starts the health server
 
 set -euo pipefail
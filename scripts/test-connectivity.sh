@@ -1,5 +1,6 @@
 #!/bin/bash
 # Test network connectivity to a host or URL
+# Description: Network connectivity test
 # Usage: test-connectivity.sh <url_or_host> [port]
 
 TARGET="$1"
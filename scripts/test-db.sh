@@ -1,5 +1,6 @@
 #!/bin/bash
 # Test database connectivity
+# Description: Database connectivity test (postgres|mysql|redis|mongodb|kafka|opensearch)
 # Usage: test-db.sh [postgres|mysql|redis|mongodb|kafka|opensearch]
 #
 # Supports both Python and Node.js runtimes - auto-detects available runtime
@@ -1,5 +1,6 @@
 #!/bin/bash
 # Test DigitalOcean Spaces connectivity
+# Description: DigitalOcean Spaces connectivity test
 # Usage: test-spaces.sh
 #
 # Required environment variables: